@@ -0,0 +1,205 @@
+// Package admin serves an HTTP API to inspect circuits of a Registry and run actions on them.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+)
+
+const (
+	actionReset         = "reset"
+	actionForceOpen     = "force-open"
+	actionForceClose    = "force-close"
+	actionDisable       = "disable"
+	actionClearOverride = "clear-override"
+)
+
+// Circuit is the report of a circuit.
+type Circuit struct {
+	Service  string `json:"service"`
+	State    string `json:"state"`
+	Override string `json:"override"`
+	// OpenRemainingMs is the time left until an open circuit moves to half open state, in milliseconds.
+	OpenRemainingMs int64      `json:"openRemainingMs"`
+	Failure         int64      `json:"failure"`
+	Success         int64      `json:"success"`
+	Slow            int64      `json:"slow"`
+	Fallback        int64      `json:"fallback"`
+	Rejected        int64      `json:"rejected"`
+	Degraded        bool       `json:"degraded"`
+	Thresholds      Thresholds `json:"thresholds"`
+}
+
+// Thresholds of a circuit storage, they are zero if storage does not expose them.
+type Thresholds struct {
+	FailureRateThreshold     int64 `json:"failureRateThreshold"`
+	SuccessRateThreshold     int64 `json:"successRateThreshold"`
+	SlowCallRateThreshold    int64 `json:"slowCallRateThreshold"`
+	MinimumNumberOfCalls     int64 `json:"minimumNumberOfCalls"`
+	PermittedCallsInHalfOpen int64 `json:"permittedCallsInHalfOpen"`
+	OpenWindowMs             int64 `json:"openWindowMs"`
+	HalfOpenWindowMs         int64 `json:"halfOpenWindowMs"`
+}
+
+// Error is the response of failed requests.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Handler is an admin http.Handler of circuits in a Registry.
+// GET / lists all circuits, and POST /{action}?service={key}&ttl={duration} runs an action on a circuit,
+// actions are reset, force-open, force-close, disable and clear-override, ttl is optional and used by overrides.
+// mount it with http.StripPrefix, like mux.Handle("/circuits/", http.StripPrefix("/circuits", handler)).
+type Handler struct {
+	registry *circuitbreaker.Registry
+	ops      Options
+}
+
+// NewHandler create new instance of Handler.
+func NewHandler(registry *circuitbreaker.Registry, options ...Option) *Handler {
+	handler := Handler{registry: registry}
+
+	for _, op := range options {
+		op(&handler.ops)
+	}
+
+	return &handler
+}
+
+// ServeHTTP serves the admin requests.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(r.URL.Path, "/")
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, h.list(r.Context()))
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+		return
+	}
+
+	if h.ops.Authorizer == nil || !h.ops.Authorizer(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+
+		return
+	}
+
+	h.act(w, r, action)
+}
+
+func (h *Handler) list(ctx context.Context) []Circuit {
+	circuits := make([]Circuit, 0)
+
+	for _, key := range h.registry.Keys() {
+		if circuit, ok := h.registry.Lookup(key); ok {
+			circuits = append(circuits, report(ctx, key, circuit))
+		}
+	}
+
+	return circuits
+}
+
+func (h *Handler) act(w http.ResponseWriter, r *http.Request, action string) {
+	service := r.URL.Query().Get("service")
+
+	circuit, ok := h.registry.Lookup(service)
+	if !ok {
+		writeError(w, http.StatusNotFound, "circuit not found")
+
+		return
+	}
+
+	var ttl time.Duration
+
+	if value := r.URL.Query().Get("ttl"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil || ttl < 0 {
+			writeError(w, http.StatusBadRequest, "invalid ttl")
+
+			return
+		}
+	}
+
+	var err error
+
+	switch action {
+	case actionReset:
+		err = circuit.Reset(r.Context())
+	case actionForceOpen:
+		err = circuit.ForceOpen(r.Context(), ttl)
+	case actionForceClose:
+		err = circuit.ForceClose(r.Context(), ttl)
+	case actionDisable:
+		err = circuit.Disable(r.Context(), ttl)
+	case actionClearOverride:
+		err = circuit.ClearOverride(r.Context())
+	default:
+		writeError(w, http.StatusNotFound, "action not found")
+
+		return
+	}
+
+	switch {
+	case errors.Is(err, circuitbreaker.ErrOverrideNotSupported):
+		writeError(w, http.StatusNotImplemented, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, report(r.Context(), service, circuit))
+	}
+}
+
+func report(ctx context.Context, service string, circuit *circuitbreaker.Circuit) Circuit {
+	stat := circuit.Stat(ctx)
+	options := circuit.StorageOptions()
+
+	return Circuit{
+		Service:         service,
+		State:           circuitbreaker.GetStateText(stat.State),
+		Override:        circuitbreaker.GetOverrideText(circuit.Override(ctx)),
+		OpenRemainingMs: circuit.OpenRemaining(ctx).Milliseconds(),
+		Failure:         stat.Failure,
+		Success:         stat.Success,
+		Slow:            stat.Slow,
+		Fallback:        stat.Fallback,
+		Rejected:        stat.Rejected,
+		Degraded:        stat.Degraded,
+		Thresholds: Thresholds{
+			FailureRateThreshold:     options.FailureRateThreshold,
+			SuccessRateThreshold:     options.SuccessRateThreshold,
+			SlowCallRateThreshold:    options.SlowCallRateThreshold,
+			MinimumNumberOfCalls:     options.MinimumNumberOfCalls,
+			PermittedCallsInHalfOpen: options.PermittedCallsInHalfOpen,
+			OpenWindowMs:             options.OpenWindow.Milliseconds(),
+			HalfOpenWindowMs:         options.HalfOpenWindow.Milliseconds(),
+		},
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, Error{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(value)
+}
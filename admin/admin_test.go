@@ -0,0 +1,141 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/admin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	registry := circuitbreaker.NewRegistry(
+		circuitbreaker.WithStorageOptions(
+			circuitbreaker.WithFailureRateThreshold(1),
+			circuitbreaker.WithOpenWindow(time.Minute),
+			circuitbreaker.WithHalfOpenWindow(5*time.Second),
+		),
+		circuitbreaker.WithKeyOptions("orders", circuitbreaker.WithBulkhead(1)),
+	)
+	defer registry.Close()
+
+	handler := admin.NewHandler(registry, admin.WithToken("secret"))
+
+	serve := func(method, target, token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	registry.Get("users").Done(context.Background(), errors.New("some error"))
+	rejectCall(t, registry.Get("orders"))
+
+	t.Run("expect circuits to be listed with their stat and thresholds", func(t *testing.T) {
+		recorder := serve(http.MethodGet, "/", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var circuits []admin.Circuit
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&circuits))
+		assert.Len(t, circuits, 2)
+
+		assert.Equal(t, "orders", circuits[0].Service)
+		assert.Equal(t, "Close", circuits[0].State)
+		assert.Equal(t, int64(1), circuits[0].Success)
+		assert.Equal(t, int64(1), circuits[0].Rejected)
+
+		assert.Equal(t, "users", circuits[1].Service)
+		assert.Equal(t, "Open", circuits[1].State)
+		assert.Equal(t, "None", circuits[1].Override)
+		assert.Equal(t, int64(1), circuits[1].Failure)
+		assert.True(t, circuits[1].OpenRemainingMs > 50000 && circuits[1].OpenRemainingMs <= 55000)
+		assert.Equal(t, admin.Thresholds{
+			FailureRateThreshold: 1,
+			SuccessRateThreshold: circuitbreaker.DefaultSuccessRateThreshold,
+			MinimumNumberOfCalls: circuitbreaker.DefaultMinimumNumberOfCalls,
+			OpenWindowMs:         60000,
+			HalfOpenWindowMs:     5000,
+		}, circuits[1].Thresholds)
+	})
+
+	t.Run("action without valid token, expect unauthorized", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/reset?service=users", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/reset?service=users", "wrong").Code)
+
+		request := httptest.NewRequest(http.MethodPost, "/reset?service=users", nil)
+		request.Header.Set("Authorization", "secret")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+		assert.Equal(t, circuitbreaker.StateOpen, registry.Get("users").GetState(context.Background()))
+	})
+
+	t.Run("reset, expect circuit to be close", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/reset?service=users", "secret")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, circuitbreaker.StateClose, registry.Get("users").GetState(context.Background()))
+	})
+
+	t.Run("force open with ttl, expect circuit to be open", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/force-open?service=orders&ttl=10m", "secret")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var circuit admin.Circuit
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&circuit))
+		assert.Equal(t, "Open", circuit.State)
+		assert.Equal(t, "ForceOpen", circuit.Override)
+		assert.False(t, registry.Get("orders").IsAvailable(context.Background()))
+	})
+
+	t.Run("force close, expect circuit to be close", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/force-close?service=orders", "secret").Code)
+		assert.True(t, registry.Get("orders").IsAvailable(context.Background()))
+		assert.Equal(t, circuitbreaker.OverrideForceClose, registry.Get("orders").Override(context.Background()))
+	})
+
+	t.Run("invalid requests, expect errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/reset?service=unknown", "secret").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/unknown?service=users", "secret").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/force-open?service=users&ttl=soon", "secret").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/reset?service=users", "secret").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/", "secret").Code)
+	})
+}
+
+// rejectCall of circuit by its bulkhead of one call, while a call is running that succeeds.
+func rejectCall(t *testing.T, circuit *circuitbreaker.Circuit) {
+	t.Helper()
+
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_, _ = circuit.Do(context.Background(), func() (interface{}, error) {
+			close(started)
+			<-release
+
+			return nil, nil
+		})
+	}()
+
+	<-started
+
+	_, err := circuit.Do(context.Background(), func() (interface{}, error) { return nil, nil })
+	assert.ErrorIs(t, err, circuitbreaker.ErrBulkheadFull)
+
+	close(release)
+	<-done
+}
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Authorizer reports if request is allowed to run actions on circuits.
+type Authorizer func(r *http.Request) bool
+
+// BearerToken authorizes requests with the token in their Authorization header, as "Bearer <token>".
+func BearerToken(token string) Authorizer {
+	return func(r *http.Request) bool {
+		header := r.Header.Get("Authorization")
+		if token == "" || !strings.HasPrefix(header, "Bearer ") {
+			return false
+		}
+
+		return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), []byte(token)) == 1
+	}
+}
+
+// Options is options of Handler.
+type Options struct {
+	// Authorizer authorizes POST actions, if its nil all actions are rejected.
+	Authorizer Authorizer
+}
+
+// Option configures Handler.
+type Option func(*Options)
+
+// WithAuthorizer sets the function that authorizes POST actions.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(o *Options) {
+		o.Authorizer = authorizer
+	}
+}
+
+// WithToken authorizes POST actions with a bearer token.
+func WithToken(token string) Option {
+	return WithAuthorizer(BearerToken(token))
+}
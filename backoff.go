@@ -0,0 +1,39 @@
+package circuitbreaker
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// backoffOpenWindow is the open window after trips consecutive failures in half open state, without jitter.
+func backoffOpenWindow(trips int64, options StorageOptions) time.Duration {
+	window := float64(options.OpenWindow)
+
+	if options.OpenWindowMultiplier > 1 && trips > 0 {
+		window *= math.Pow(options.OpenWindowMultiplier, float64(trips))
+	}
+
+	if options.MaxOpenWindow > 0 && window > float64(options.MaxOpenWindow) {
+		return options.MaxOpenWindow
+	}
+
+	return time.Duration(window)
+}
+
+// jitter is the random fraction of open window that is cut from it.
+func jitter(options StorageOptions) float64 {
+	if options.OpenWindowJitter <= 0 {
+		return 0
+	}
+
+	// nolint:gosec
+	return rand.Float64() * math.Min(options.OpenWindowJitter, 1)
+}
+
+// openWindow is the open window after trips consecutive failures in half open state.
+func openWindow(trips int64, options StorageOptions) time.Duration {
+	window := backoffOpenWindow(trips, options)
+
+	return window - time.Duration(float64(window)*jitter(options))
+}
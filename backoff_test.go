@@ -0,0 +1,43 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffOpenWindow(t *testing.T) {
+	options := StorageOptions{OpenWindow: time.Minute, OpenWindowMultiplier: 2, MaxOpenWindow: 5 * time.Minute}
+
+	assert.Equal(t, time.Minute, backoffOpenWindow(0, options))
+	assert.Equal(t, 2*time.Minute, backoffOpenWindow(1, options))
+	assert.Equal(t, 4*time.Minute, backoffOpenWindow(2, options))
+	assert.Equal(t, 5*time.Minute, backoffOpenWindow(3, options), "open window is limited to max")
+	assert.Equal(t, time.Minute, backoffOpenWindow(3, StorageOptions{OpenWindow: time.Minute}), "backoff is not set")
+}
+
+func TestOpenWindow_Jitter(t *testing.T) {
+	options := StorageOptions{OpenWindow: time.Minute, OpenWindowJitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		window := openWindow(0, options)
+		assert.True(t, window > 30*time.Second && window <= time.Minute, "window %s is out of jitter range", window)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	options := Options{RetryBackoff: 100 * time.Millisecond, MaxRetryBackoff: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, retryBackoff(1, options))
+	assert.Equal(t, 200*time.Millisecond, retryBackoff(2, options))
+	assert.Equal(t, 800*time.Millisecond, retryBackoff(4, options))
+	assert.Equal(t, time.Second, retryBackoff(5, options), "backoff is limited to max")
+
+	options.RetryJitter = 0.5
+
+	for i := 0; i < 100; i++ {
+		backoff := retryBackoff(1, options)
+		assert.True(t, backoff > 50*time.Millisecond && backoff <= 100*time.Millisecond, "backoff %s is out of jitter range", backoff)
+	}
+}
@@ -0,0 +1,98 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull meant max concurrent calls are running and the call is rejected.
+var ErrBulkheadFull = errors.New("CircuitBreaker: max concurrent calls reached")
+
+// bulkhead limits concurrent calls with a semaphore, calls that can not acquire it may wait in a bounded queue.
+type bulkhead struct {
+	slots   chan struct{}
+	waiting int64
+	// maxWaiting is size of the queue, and timeout is the max wait of each call in it.
+	maxWaiting int64
+	timeout    time.Duration
+}
+
+func newBulkhead(options Options) *bulkhead {
+	if options.MaxConcurrentCalls <= 0 {
+		return nil
+	}
+
+	return &bulkhead{
+		slots:      make(chan struct{}, options.MaxConcurrentCalls),
+		maxWaiting: options.MaxWaitingCalls,
+		timeout:    options.MaxWaitDuration,
+	}
+}
+
+// acquire a slot, it returns ErrBulkheadFull if the queue is full or wait is timed out,
+// and error of ctx if it is done while waiting.
+func (b *bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if atomic.AddInt64(&b.waiting, 1) > b.maxWaiting {
+		atomic.AddInt64(&b.waiting, -1)
+
+		return ErrBulkheadFull
+	}
+
+	defer atomic.AddInt64(&b.waiting, -1)
+
+	var timeout <-chan time.Time
+
+	if b.timeout > 0 {
+		timer := time.NewTimer(b.timeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.slots
+}
+
+// acquireBulkhead slot for a call, rejections are counted in Stat and stored as failures if BulkheadFailures is set.
+func (s *Circuit) acquireBulkhead(ctx context.Context) error {
+	if s.bulkhead == nil {
+		return nil
+	}
+
+	err := s.bulkhead.acquire(ctx)
+	if !errors.Is(err, ErrBulkheadFull) {
+		return err
+	}
+
+	atomic.AddInt64(&s.rejected, 1)
+
+	if s.ops.BulkheadFailures {
+		s.storeFailure(ctx)
+	}
+
+	return err
+}
+
+func (s *Circuit) releaseBulkhead() {
+	if s.bulkhead != nil {
+		s.bulkhead.release()
+	}
+}
@@ -0,0 +1,133 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_Bulkhead(t *testing.T) {
+	storage := []circuitbreaker.StorageOption{
+		circuitbreaker.WithFailureRateThreshold(1),
+		circuitbreaker.WithSuccessRateThreshold(10),
+		circuitbreaker.WithOpenWindow(time.Minute),
+	}
+
+	// block runs a call that holds a slot until the returned function is called.
+	block := func(breaker *circuitbreaker.Circuit) func() {
+		started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			_, _ = breaker.Do(context.Background(), func() (interface{}, error) {
+				close(started)
+				<-release
+
+				return nil, nil
+			})
+		}()
+
+		<-started
+
+		return func() {
+			close(release)
+			<-done
+		}
+	}
+
+	fn := func() (interface{}, error) { return "response", nil }
+
+	t.Run("max concurrent calls are running, expect call to be rejected", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithBulkhead(1))
+		release := block(breaker)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrBulkheadFull, err)
+
+		release()
+
+		res, err := breaker.Do(context.Background(), fn)
+		assert.Nil(t, err)
+		assert.Equal(t, "response", res)
+
+		stat := breaker.Stat(context.Background())
+		assert.Equal(t, int64(1), stat.Rejected)
+		assert.Equal(t, int64(0), stat.Failure)
+		assert.Equal(t, circuitbreaker.StateClose, stat.State)
+	})
+
+	t.Run("call waits in queue, expect it to run when a slot is freed", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithBulkhead(1), circuitbreaker.WithBulkheadQueue(1, time.Second))
+		release := block(breaker)
+
+		time.AfterFunc(10*time.Millisecond, release)
+
+		res, err := breaker.Do(context.Background(), fn)
+		assert.Nil(t, err)
+		assert.Equal(t, "response", res)
+		assert.Equal(t, int64(0), breaker.Stat(context.Background()).Rejected)
+	})
+
+	t.Run("wait is timed out, expect call to be rejected", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithBulkhead(1), circuitbreaker.WithBulkheadQueue(1, 10*time.Millisecond))
+		release := block(breaker)
+		defer release()
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrBulkheadFull, err)
+		assert.Equal(t, int64(1), breaker.Stat(context.Background()).Rejected)
+	})
+
+	t.Run("ctx is canceled while waiting, expect ctx error and rejection to not be counted", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithBulkhead(1), circuitbreaker.WithBulkheadQueue(1, 0))
+		release := block(breaker)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := breaker.Do(ctx, fn)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, int64(0), breaker.Stat(context.Background()).Rejected)
+	})
+
+	t.Run("circuit is half open, expect rejected calls to not take trial calls", func(t *testing.T) {
+		halfOpen := append(storage[:len(storage):len(storage)],
+			circuitbreaker.WithHalfOpenWindow(time.Minute),
+			circuitbreaker.WithPermittedCallsInHalfOpen(2),
+		)
+		breaker := newMemoryCircuit(halfOpen, circuitbreaker.WithBulkhead(1))
+
+		breaker.Done(context.Background(), errors.New("some error"))
+		assert.Equal(t, circuitbreaker.StateHalfOpen, breaker.GetState(context.Background()))
+
+		release := block(breaker)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrBulkheadFull, err)
+
+		release()
+
+		res, err := breaker.Do(context.Background(), fn)
+		assert.Nil(t, err)
+		assert.Equal(t, "response", res)
+	})
+
+	t.Run("rejections are failures, expect rejection to open circuit", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithBulkhead(1), circuitbreaker.WithBulkheadFailures())
+		release := block(breaker)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrBulkheadFull, err)
+
+		release()
+
+		_, err = breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+	})
+}
@@ -0,0 +1,325 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is how long state of CachedStorage is served from cache.
+	DefaultCacheTTL = time.Second / 10
+
+	// DefaultFlushInterval is how long CachedStorage batches call results before writing them to storage.
+	DefaultFlushInterval = time.Second / 10
+)
+
+var (
+	_ Storage           = &CachedStorage{}
+	_ WindowedStorage   = &CachedStorage{}
+	_ HalfOpenLimiter   = &CachedStorage{}
+	_ SlowCallStorage   = &CachedStorage{}
+	_ OpenWindowStorage = &CachedStorage{}
+	_ StateNotifier     = &CachedStorage{}
+	_ OverrideStorage   = &CachedStorage{}
+	_ OptionsStorage    = &CachedStorage{}
+)
+
+// CacheOptions is CachedStorage options.
+type CacheOptions struct {
+	// TTL is how long state is served from cache, so it is the maximum staleness of state changes made by other instances
+	// if its 0, then state is always read from storage
+	TTL time.Duration
+	// FlushInterval is how long successes are batched before writing them to storage
+	// if its 0, then call results are written to storage synchronously
+	FlushInterval time.Duration
+	// Logger is used to log errors of background flushes
+	Logger Logger
+}
+
+// CacheOption configures the CachedStorage.
+type CacheOption func(*CacheOptions)
+
+// WithCacheTTL sets how long state is served from cache.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *CacheOptions) {
+		o.TTL = ttl
+	}
+}
+
+// WithFlushInterval sets how long successes are batched before writing them to storage.
+func WithFlushInterval(interval time.Duration) CacheOption {
+	return func(o *CacheOptions) {
+		o.FlushInterval = interval
+	}
+}
+
+// WithCacheLogger sets the logger of background flush errors.
+func WithCacheLogger(logger Logger) CacheOption {
+	return func(o *CacheOptions) {
+		o.Logger = logger
+	}
+}
+
+// CachedStorage is a Storage decorator that serves state from an in-process cache and batches call results,
+// it is useful in front of a distributed storage like RedisStorage, and is concurrent safe.
+// failures and slow calls invalidate the cache, so the next GetState writes them and reads the fresh state,
+// successes are written in background every FlushInterval, and the cache is invalidated after them.
+// call Close to stop it and write pending results.
+type CachedStorage struct {
+	storage Storage
+	options CacheOptions
+
+	pending pendingResults
+
+	lock     sync.Mutex
+	state    State
+	cachedAt time.Time
+	valid    bool
+
+	now       func() time.Time
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCachedStorage create new instance of CachedStorage in front of storage.
+func NewCachedStorage(storage Storage, options ...CacheOption) *CachedStorage {
+	cached := CachedStorage{
+		storage: storage,
+		options: CacheOptions{
+			TTL:           DefaultCacheTTL,
+			FlushInterval: DefaultFlushInterval,
+			Logger:        NewIOLogger(os.Stdout, OutPutTypeSimple),
+		},
+		now:     time.Now,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	for _, op := range options {
+		op(&cached.options)
+	}
+
+	if cached.options.FlushInterval > 0 {
+		go cached.flushLoop()
+	} else {
+		close(cached.stopped)
+	}
+
+	return &cached
+}
+
+// Failure is responsible to store failures.
+func (c *CachedStorage) Failure(ctx context.Context, delta int64) error {
+	if c.options.FlushInterval <= 0 {
+		defer c.invalidate()
+
+		return c.storage.Failure(ctx, delta)
+	}
+
+	c.pending.failures.Add(delta)
+	c.invalidate()
+
+	return nil
+}
+
+// Slow is responsible to store slow calls.
+func (c *CachedStorage) Slow(ctx context.Context, delta int64) error {
+	if c.options.FlushInterval <= 0 {
+		defer c.invalidate()
+
+		return slowCall(ctx, c.storage, delta)
+	}
+
+	c.pending.slow.Add(delta)
+	c.invalidate()
+
+	return nil
+}
+
+// Success is responsible to store success.
+func (c *CachedStorage) Success(ctx context.Context, delta int64) error {
+	if c.options.FlushInterval <= 0 {
+		defer c.invalidate()
+
+		return c.storage.Success(ctx, delta)
+	}
+
+	c.pending.success.Add(delta)
+
+	return nil
+}
+
+// GetState from cache, or from storage if cache is expired or invalidated.
+func (c *CachedStorage) GetState(ctx context.Context) (State, error) {
+	c.lock.Lock()
+	if c.valid && c.now().Sub(c.cachedAt) < c.options.TTL {
+		state := c.state
+		c.lock.Unlock()
+
+		return state, nil
+	}
+	c.lock.Unlock()
+
+	if err := c.Flush(ctx); err != nil {
+		return StateClose, err
+	}
+
+	state, err := c.storage.GetState(ctx)
+	if err != nil {
+		return state, err
+	}
+
+	c.lock.Lock()
+	c.state, c.cachedAt, c.valid = state, c.now(), true
+	c.lock.Unlock()
+
+	return state, nil
+}
+
+// Reset the state, pending call results are dropped.
+func (c *CachedStorage) Reset(ctx context.Context) error {
+	c.pending.reset()
+	c.invalidate()
+
+	return c.storage.Reset(ctx)
+}
+
+// StorageOptions of storage, they are zero if storage does not expose them.
+func (c *CachedStorage) StorageOptions() StorageOptions {
+	storage, ok := c.storage.(OptionsStorage)
+	if !ok {
+		return StorageOptions{}
+	}
+
+	return storage.StorageOptions()
+}
+
+// Windowed reports if storage use a sliding window.
+func (c *CachedStorage) Windowed() bool {
+	storage, ok := c.storage.(WindowedStorage)
+
+	return ok && storage.Windowed()
+}
+
+// AcquireHalfOpen reports if one more trial call is permitted, it is always permitted if storage does not limit them.
+func (c *CachedStorage) AcquireHalfOpen(ctx context.Context) (bool, error) {
+	limiter, ok := c.storage.(HalfOpenLimiter)
+	if !ok {
+		return true, nil
+	}
+
+	return limiter.AcquireHalfOpen(ctx)
+}
+
+// OpenRemaining is the time left until the circuit moves to half open state, it is zero if storage does not know it.
+func (c *CachedStorage) OpenRemaining(ctx context.Context) (time.Duration, error) {
+	storage, ok := c.storage.(OpenWindowStorage)
+	if !ok {
+		return 0, nil
+	}
+
+	return storage.OpenRemaining(ctx)
+}
+
+// SetOverride of storage and invalidate the cache, so it is applied immediately on this instance.
+func (c *CachedStorage) SetOverride(ctx context.Context, override Override, ttl time.Duration) error {
+	storage, ok := c.storage.(OverrideStorage)
+	if !ok {
+		return ErrOverrideNotSupported
+	}
+
+	c.invalidate()
+
+	return storage.SetOverride(ctx, override, ttl)
+}
+
+// GetOverride of storage, it is OverrideNone if storage does not support overrides.
+func (c *CachedStorage) GetOverride(ctx context.Context) (Override, error) {
+	storage, ok := c.storage.(OverrideStorage)
+	if !ok {
+		return OverrideNone, nil
+	}
+
+	return storage.GetOverride(ctx)
+}
+
+// NotifyState calls fn with the new state on each change that storage notifies, and keeps the cache up to date with it.
+func (c *CachedStorage) NotifyState(fn func(state State)) func() {
+	notifier, ok := c.storage.(StateNotifier)
+	if !ok {
+		return func() {}
+	}
+
+	return notifier.NotifyState(func(state State) {
+		c.notified(state)
+		fn(state)
+	})
+}
+
+// notified state is cached, unless there are pending failures that may change it.
+func (c *CachedStorage) notified(state State) {
+	if c.pending.failing() {
+		return
+	}
+
+	c.lock.Lock()
+	c.state, c.cachedAt, c.valid = state, c.now(), true
+	c.lock.Unlock()
+}
+
+// Flush writes pending call results to storage, successes are written first.
+// results that failed to be written are kept for the next flush, and if any is written, the cache is invalidated
+// as the state of storage may be changed by them.
+func (c *CachedStorage) Flush(ctx context.Context) error {
+	written, err := c.pending.flush(ctx, c.storage)
+	if written {
+		c.invalidate()
+	}
+
+	return err
+}
+
+// Close stops background flushes and writes pending call results to storage, then closes storage if it needs to be closed.
+func (c *CachedStorage) Close() error {
+	c.closeOnce.Do(func() { close(c.stop) })
+	<-c.stopped
+
+	if err := c.Flush(context.Background()); err != nil {
+		return err
+	}
+
+	if closer, ok := c.storage.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (c *CachedStorage) invalidate() {
+	c.lock.Lock()
+	c.valid = false
+	c.lock.Unlock()
+}
+
+func (c *CachedStorage) flushLoop() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				c.options.Logger.Error(fmt.Errorf("flushing cached storage: %w", err))
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedStorage_GetState(t *testing.T) {
+	storage := &mock.Storage{}
+	cached := circuitbreaker.NewCachedStorage(storage, circuitbreaker.WithCacheTTL(time.Hour), circuitbreaker.WithFlushInterval(time.Hour))
+	defer cached.Close()
+
+	t.Run("expect state to be read from storage once and then served from cache", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+
+		for i := 0; i < 3; i++ {
+			state, err := cached.GetState(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, circuitbreaker.StateClose, state)
+		}
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("successes are stored, expect them to be batched and cache to be kept until they are flushed", func(t *testing.T) {
+		assert.Nil(t, cached.Success(context.Background(), 1))
+		assert.Nil(t, cached.Success(context.Background(), 2))
+
+		state, err := cached.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		storage.On("Success", context.Background(), int64(3)).Return(nil).Once()
+		assert.Nil(t, cached.Flush(context.Background()))
+
+		// flushed successes may close the circuit, so state is read again.
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+
+		state, err = cached.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("failures are stored, expect them to be written and state to be read on next get state", func(t *testing.T) {
+		assert.Nil(t, cached.Failure(context.Background(), 1))
+		assert.Nil(t, cached.Slow(context.Background(), 1))
+
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Twice()
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateOpen, nil).Once()
+
+		state, err := cached.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("writing pending results failed, expect error and results to be kept for next flush", func(t *testing.T) {
+		assert.Nil(t, cached.Failure(context.Background(), 2))
+
+		storage.On("Failure", context.Background(), int64(2)).Return(errors.New("some error")).Once()
+
+		_, err := cached.GetState(context.Background())
+		assert.NotNil(t, err)
+
+		storage.On("Failure", context.Background(), int64(2)).Return(nil).Once()
+		assert.Nil(t, cached.Flush(context.Background()))
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestCachedStorage_TTL(t *testing.T) {
+	storage := &mock.Storage{}
+	cached := circuitbreaker.NewCachedStorage(storage, circuitbreaker.WithCacheTTL(time.Millisecond), circuitbreaker.WithFlushInterval(0))
+	defer cached.Close()
+
+	t.Run("cache is expired, expect state to be read from storage again", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Twice()
+
+		_, err := cached.GetState(context.Background())
+		assert.Nil(t, err)
+
+		time.Sleep(2 * time.Millisecond)
+
+		_, err = cached.GetState(context.Background())
+		assert.Nil(t, err)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("flush interval is not set, expect results to be written synchronously", func(t *testing.T) {
+		storage.On("Success", context.Background(), int64(1)).Return(nil).Once()
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Twice()
+
+		assert.Nil(t, cached.Success(context.Background(), 1))
+		assert.Nil(t, cached.Failure(context.Background(), 1))
+		assert.Nil(t, cached.Slow(context.Background(), 1))
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestCachedStorage_Close(t *testing.T) {
+	storage := &mock.Storage{}
+	cached := circuitbreaker.NewCachedStorage(storage, circuitbreaker.WithFlushInterval(time.Hour))
+
+	assert.Nil(t, cached.Success(context.Background(), 2))
+
+	storage.On("Success", context.Background(), int64(2)).Return(nil).Once()
+	assert.Nil(t, cached.Close())
+
+	storage.AssertExpectations(t)
+}
+
+func TestCachedStorage_Circuit(t *testing.T) {
+	cached := circuitbreaker.NewCachedStorage(
+		circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(2), circuitbreaker.WithOpenWindow(time.Minute)),
+		circuitbreaker.WithCacheTTL(time.Hour),
+	)
+	defer cached.Close()
+
+	breaker := circuitbreaker.NewCircuit(circuitbreaker.WithStorage(cached), circuitbreaker.WithFallbackState(circuitbreaker.StateClose))
+
+	t.Run("expect local failures to open the circuit immediately", func(t *testing.T) {
+		assert.True(t, breaker.IsAvailable(context.Background()))
+
+		breaker.Done(context.Background(), errors.New("some error"))
+		breaker.Done(context.Background(), errors.New("some error"))
+
+		assert.False(t, breaker.IsAvailable(context.Background()))
+	})
+}
+
+func TestCachedStorage_Override(t *testing.T) {
+	storage := circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(1), circuitbreaker.WithOpenWindow(time.Minute))
+	cached := circuitbreaker.NewCachedStorage(storage, circuitbreaker.WithCacheTTL(time.Hour), circuitbreaker.WithFlushInterval(time.Hour))
+	defer cached.Close()
+
+	state, err := cached.GetState(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateClose, state)
+
+	t.Run("override is set, expect cache to be invalidated", func(t *testing.T) {
+		assert.Nil(t, cached.SetOverride(context.Background(), circuitbreaker.OverrideForceOpen, 0))
+
+		state, err := cached.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+
+		override, err := cached.GetOverride(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.OverrideForceOpen, override)
+	})
+
+	t.Run("storage does not support overrides, expect error", func(t *testing.T) {
+		cached := circuitbreaker.NewCachedStorage(&mock.Storage{}, circuitbreaker.WithFlushInterval(0))
+
+		assert.Equal(t, circuitbreaker.ErrOverrideNotSupported, cached.SetOverride(context.Background(), circuitbreaker.OverrideForceOpen, 0))
+	})
+}
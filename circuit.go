@@ -5,31 +5,52 @@ import (
 	"errors"
 	"fmt"
 	"sync/atomic"
+	"time"
 )
 
-var _ Manager = &Circuit{}
+var _ ContextManager = &Circuit{}
 
 // ErrIsOpen meant circuit is open and can not accept any new request.
 var ErrIsOpen = errors.New("CircuitBreaker: external service dont accept new request")
 
+// ErrTimeout meant call did not finish in CallTimeout.
+var ErrTimeout = errors.New("CircuitBreaker: external service call timed out")
+
 // Fn is type of callable than Do and DoWithFallback accept.
 type Fn func() (interface{}, error)
 
+// ContextFn is type of callable that DoContext accept, ctx is canceled when the call times out.
+type ContextFn func(ctx context.Context) (interface{}, error)
+
 // Circuit is a Circuit manager.
 type Circuit struct {
-	ops     Options
-	failure int64
-	success int64
+	ops      Options
+	failure  int64
+	success  int64
+	slow     int64
+	fallback int64
+	rejected int64
+	bulkhead *bulkhead
+	// state is last observed state of storage.
+	state       int64
+	subscribers subscribers
 }
 
 // NewCircuit breaker.
 func NewCircuit(options ...Option) *Circuit {
-	circuit := Circuit{ops: Options{}}
+	circuit := Circuit{ops: Options{}, state: int64(StateUnknown)}
 
 	for _, op := range options {
 		op(&circuit.ops)
 	}
 
+	circuit.bulkhead = newBulkhead(circuit.ops)
+
+	// state changes made by other instances are observed as soon as storage notifies them.
+	if notifier, ok := circuit.ops.Storage.(StateNotifier); ok {
+		notifier.NotifyState(circuit.observe)
+	}
+
 	return &circuit
 }
 
@@ -37,6 +58,14 @@ type Stat struct {
 	State   State
 	Failure int64
 	Success int64
+	// Slow is number of calls that succeeded but took longer than SlowCallDuration
+	Slow int64
+	// Fallback is number of times that fallback of DoWithFallback is called
+	Fallback int64
+	// Rejected is number of calls that are rejected with ErrBulkheadFull
+	Rejected int64
+	// Degraded is true when storage fell back to a less accurate storage, see FailoverStorage
+	Degraded bool
 }
 
 // Manager is Circuit Breaker manager.
@@ -48,6 +77,14 @@ type Manager interface {
 	Stat(ctx context.Context) Stat
 }
 
+// ContextManager is a Manager that calls fn with a ctx that is canceled when the call times out.
+// Execute and ExecuteWithFallback use it if the manager implements it, otherwise they call Do.
+type ContextManager interface {
+	Manager
+	DoContext(ctx context.Context, fn ContextFn) (interface{}, error)
+	DoContextWithFallback(ctx context.Context, fn ContextFn, fallback FallbackFn) (interface{}, error)
+}
+
 // GetState is used to get the circuit breaker state.
 func (s *Circuit) GetState(ctx context.Context) State {
 	state, err := s.ops.Storage.GetState(ctx)
@@ -57,52 +94,187 @@ func (s *Circuit) GetState(ctx context.Context) State {
 		return StateUnknown
 	}
 
+	s.observe(state)
+
 	return state
 }
 
 // Stat of the circuit.
 func (s *Circuit) Stat(ctx context.Context) Stat {
+	return s.stat(s.GetState(ctx))
+}
+
+// LastState is the state that circuit observed when it read storage last time, storage is not read,
+// so it is cheap but may be stale. it is StateUnknown until storage is read.
+func (s *Circuit) LastState() State {
+	return State(atomic.LoadInt64(&s.state))
+}
+
+func (s *Circuit) stat(state State) Stat {
 	return Stat{
-		State:   s.GetState(ctx),
-		Failure: atomic.LoadInt64(&s.failure),
-		Success: atomic.LoadInt64(&s.success),
+		State:    state,
+		Failure:  atomic.LoadInt64(&s.failure),
+		Success:  atomic.LoadInt64(&s.success),
+		Slow:     atomic.LoadInt64(&s.slow),
+		Fallback: atomic.LoadInt64(&s.fallback),
+		Rejected: atomic.LoadInt64(&s.rejected),
+		Degraded: s.degraded(),
 	}
 }
 
+func (s *Circuit) degraded() bool {
+	storage, ok := s.ops.Storage.(DegradedStorage)
+
+	return ok && storage.Degraded()
+}
+
+// OpenRemaining is the time left until an open circuit moves to half open state and accepts trial calls.
+// it is zero if circuit is not open or storage does not know it.
+func (s *Circuit) OpenRemaining(ctx context.Context) time.Duration {
+	storage, ok := s.ops.Storage.(OpenWindowStorage)
+	if !ok {
+		return 0
+	}
+
+	remaining, err := storage.OpenRemaining(ctx)
+	if err != nil {
+		s.ops.Logger.Error(fmt.Errorf("getting open remaining time: %w", err))
+
+		return 0
+	}
+
+	return remaining
+}
+
+// StorageOptions of the circuit storage, like thresholds and windows, they are zero if storage does not expose them.
+func (s *Circuit) StorageOptions() StorageOptions {
+	storage, ok := s.ops.Storage.(OptionsStorage)
+	if !ok {
+		return StorageOptions{}
+	}
+
+	return storage.StorageOptions()
+}
+
+// Reset the circuit storage, so circuit is close, the override is kept.
+// the counters of Stat are not reset.
+func (s *Circuit) Reset(ctx context.Context) error {
+	if err := s.ops.Storage.Reset(ctx); err != nil {
+		return fmt.Errorf("resetting storage: %w", err)
+	}
+
+	s.refresh(ctx)
+
+	return nil
+}
+
 // IsAvailable checks if the service is available.
+// in half open state, it also takes one of the permitted trial calls if storage limits them.
 func (s *Circuit) IsAvailable(ctx context.Context) bool {
-	return !s.Is(ctx, StateOpen)
+	switch s.currentState(ctx) {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		return s.acquireHalfOpen(ctx)
+	}
+
+	return true
 }
 
 // Is compare current state with requested state.
 func (s *Circuit) Is(ctx context.Context, state State) (is bool) {
-	currentState, err := s.ops.Storage.GetState(ctx)
+	return s.currentState(ctx) == state
+}
+
+// currentState of storage or the fallback state if storage failed.
+func (s *Circuit) currentState(ctx context.Context) State {
+	state, err := s.ops.Storage.GetState(ctx)
 	if err != nil {
 		s.ops.Logger.Error(fmt.Errorf("checking service status: %w", err))
 
-		return state == s.ops.State
+		return s.ops.State
+	}
+
+	s.observe(state)
+
+	return state
+}
+
+func (s *Circuit) acquireHalfOpen(ctx context.Context) bool {
+	limiter, ok := s.ops.Storage.(HalfOpenLimiter)
+	if !ok {
+		return true
+	}
+
+	acquired, err := limiter.AcquireHalfOpen(ctx)
+	if err != nil {
+		s.ops.Logger.Error(fmt.Errorf("acquiring half open trial call: %w", err))
+
+		return s.ops.State != StateOpen
 	}
 
-	return currentState == state
+	return acquired
 }
 
 // Done call when operation is done/failed.
+// err is stored based on ErrorClassifier, by default all non nil errors are failures.
 func (s *Circuit) Done(ctx context.Context, err error) {
-	if err != nil {
+	s.done(ctx, err, 0)
+}
+
+func (s *Circuit) done(ctx context.Context, err error, duration time.Duration) {
+	switch s.classify(err) {
+	case OutcomeIgnore:
+		return
+	case OutcomeFailure:
 		s.doneWithError(ctx)
 
 		return
 	}
 
+	if s.ops.SlowCallDuration > 0 && duration > s.ops.SlowCallDuration {
+		s.doneSlow(ctx)
+
+		return
+	}
+
 	s.doneWithoutError(ctx)
 }
 
 func (s *Circuit) doneWithError(ctx context.Context) {
 	atomic.AddInt64(&s.failure, 1)
+	s.storeFailure(ctx)
+}
 
+func (s *Circuit) storeFailure(ctx context.Context) {
 	if err := s.ops.Storage.Failure(ctx, 1); err != nil {
 		s.ops.Logger.Error(fmt.Errorf("storing service failure: %w", err))
+
+		return
 	}
+
+	s.refresh(ctx)
+}
+
+// doneSlow store slow call, or a failure if storage does not keep slow calls apart.
+func (s *Circuit) doneSlow(ctx context.Context) {
+	atomic.AddInt64(&s.slow, 1)
+
+	var err error
+
+	if storage, ok := s.ops.Storage.(SlowCallStorage); ok {
+		err = storage.Slow(ctx, 1)
+	} else {
+		err = s.ops.Storage.Failure(ctx, 1)
+	}
+
+	if err != nil {
+		s.ops.Logger.Error(fmt.Errorf("storing service slow call: %w", err))
+
+		return
+	}
+
+	s.refresh(ctx)
 }
 
 func (s *Circuit) doneWithoutError(ctx context.Context) {
@@ -113,24 +285,80 @@ func (s *Circuit) doneWithoutError(ctx context.Context) {
 		s.ops.Logger.Error(fmt.Errorf("getting service status: %w", err))
 
 		state = s.ops.State
+	} else {
+		s.observe(state)
 	}
 
-	if state == StateClose {
+	if state == StateClose && !s.windowed() {
 		return
 	}
 
 	if err := s.ops.Storage.Success(ctx, 1); err != nil {
 		s.ops.Logger.Error(fmt.Errorf("storing service success: %w", err))
+
+		return
 	}
+
+	s.refresh(ctx)
+}
+
+// windowed reports if storage needs successes of close state to evaluate the failure rate.
+func (s *Circuit) windowed() bool {
+	storage, ok := s.ops.Storage.(WindowedStorage)
+
+	return ok && storage.Windowed()
 }
 
 // Do check circuit state and call fn is not open.
+// if SlowCallDuration is set, calls taking longer than it are stored as slow calls.
+// if CallTimeout is set, Do returns ErrTimeout when fn does not finish in time.
+// if MaxConcurrentCalls is set, Do returns ErrBulkheadFull when they are running and no slot is freed in time.
 func (s *Circuit) Do(ctx context.Context, fn Fn) (res interface{}, err error) {
+	return s.DoContext(ctx, func(context.Context) (interface{}, error) { return fn() })
+}
+
+// DoContext is like Do, but fn get a ctx that is canceled when the call times out.
+// if the ctx of caller is canceled, the call result is not stored.
+// if RetryAttempts is set, failed calls are tried again while circuit is not open, see WithRetry and ContextWithoutRetry.
+func (s *Circuit) DoContext(ctx context.Context, fn ContextFn) (interface{}, error) {
+	if s.ops.RetryAttempts > 1 && !retryDisabled(ctx) {
+		return s.retry(ctx, fn)
+	}
+
+	return s.attempt(ctx, fn)
+}
+
+// attempt a call if circuit is available and store its result.
+// bulkhead slot is acquired first, so a rejected call does not take a trial call of half open state.
+func (s *Circuit) attempt(ctx context.Context, fn ContextFn) (res interface{}, err error) {
+	if err := s.acquireBulkhead(ctx); err != nil {
+		return nil, err
+	}
+
+	defer s.releaseBulkhead()
+
 	if !s.IsAvailable(ctx) {
 		return nil, ErrIsOpen
 	}
 
-	defer func() { s.Done(ctx, err) }()
+	start := time.Now()
+
+	defer func() {
+		// caller gave up, so the error does not say anything about the service.
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+
+		if errors.Is(err, ErrTimeout) && s.ops.IgnoreTimeout {
+			return
+		}
+
+		s.done(ctx, err, time.Since(start))
+	}()
+
+	if s.ops.CallTimeout <= 0 {
+		return fn(ctx)
+	}
 
-	return fn()
+	return callWithTimeout(ctx, s.ops.CallTimeout, fn)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/mrsoftware/circuitbreaker"
 	"github.com/mrsoftware/circuitbreaker/mock"
@@ -228,3 +229,211 @@ func TestCircuitBreaker_Stat(t *testing.T) {
 		storage.AssertExpectations(t)
 	})
 }
+
+func TestCircuitBreaker_SlidingWindow(t *testing.T) {
+	t.Run("expect successes of close state to be counted in failure rate", func(t *testing.T) {
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.StorageWithDefaultOptions(),
+			circuitbreaker.WithFailureRateThreshold(50),
+			circuitbreaker.WithCountBasedSlidingWindow(4),
+			circuitbreaker.WithMinimumNumberOfCalls(4),
+		)
+
+		breaker := circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		)
+
+		breaker.Done(context.Background(), nil)
+		breaker.Done(context.Background(), nil)
+		breaker.Done(context.Background(), nil)
+		breaker.Done(context.Background(), errors.New("some error"))
+		assert.True(t, breaker.IsAvailable(context.Background()))
+
+		breaker.Done(context.Background(), errors.New("some error"))
+		assert.False(t, breaker.IsAvailable(context.Background()))
+	})
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	t.Run("expect only permitted trial calls to pass in half open state", func(t *testing.T) {
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(1),
+			circuitbreaker.WithSuccessRateThreshold(2),
+			circuitbreaker.WithOpenWindow(time.Minute),
+			circuitbreaker.WithHalfOpenWindow(time.Minute),
+			circuitbreaker.WithPermittedCallsInHalfOpen(2),
+		)
+
+		breaker := circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		)
+
+		breaker.Done(context.Background(), errors.New("some error"))
+		assert.True(t, breaker.Is(context.Background(), circuitbreaker.StateHalfOpen))
+
+		fn := func() (interface{}, error) { return "response", nil }
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Nil(t, err)
+
+		assert.True(t, breaker.IsAvailable(context.Background()))
+		assert.False(t, breaker.IsAvailable(context.Background()))
+
+		_, err = breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+	})
+
+	t.Run("failure below threshold, expect calls to not be limited in half open tail", func(t *testing.T) {
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(50),
+			circuitbreaker.WithSuccessRateThreshold(2),
+			circuitbreaker.WithOpenWindow(time.Minute),
+			circuitbreaker.WithHalfOpenWindow(time.Minute),
+			circuitbreaker.WithPermittedCallsInHalfOpen(2),
+		)
+
+		breaker := circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		)
+
+		breaker.Done(context.Background(), errors.New("some error"))
+
+		for i := 0; i < 10; i++ {
+			assert.True(t, breaker.IsAvailable(context.Background()))
+		}
+	})
+}
+
+func TestCircuitBreaker_SlowCall(t *testing.T) {
+	t.Run("call takes longer than slow call duration, expect to store slow call", func(t *testing.T) {
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.StorageWithDefaultOptions(),
+			circuitbreaker.WithSlowCallRateThreshold(1),
+		)
+
+		breaker := circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+			circuitbreaker.WithSlowCallDuration(time.Millisecond),
+		)
+
+		_, err := breaker.Do(context.Background(), func() (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+
+			return "response", nil
+		})
+		assert.Nil(t, err)
+
+		stat := breaker.Stat(context.Background())
+		assert.Equal(t, circuitbreaker.Stat{Slow: 1, State: circuitbreaker.StateOpen}, stat)
+	})
+
+	t.Run("storage does not keep slow calls apart, expect to store a failure", func(t *testing.T) {
+		storage := &mock.Storage{}
+
+		breaker := circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+			circuitbreaker.WithSlowCallDuration(time.Millisecond),
+		)
+
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+
+		_, err := breaker.Do(context.Background(), func() (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+
+			return "response", nil
+		})
+		assert.Nil(t, err)
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestCircuitBreaker_Override(t *testing.T) {
+	newBreaker := func() *circuitbreaker.Circuit {
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(1),
+			circuitbreaker.WithSuccessRateThreshold(1),
+			circuitbreaker.WithOpenWindow(time.Minute),
+			circuitbreaker.WithHalfOpenWindow(time.Second),
+		)
+
+		return circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		)
+	}
+
+	fn := func() (interface{}, error) { return nil, errors.New("some error") }
+
+	t.Run("force open, expect calls to be rejected", func(t *testing.T) {
+		breaker := newBreaker()
+
+		assert.Nil(t, breaker.ForceOpen(context.Background(), 0))
+		assert.Equal(t, circuitbreaker.OverrideForceOpen, breaker.Override(context.Background()))
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+
+		assert.Nil(t, breaker.ClearOverride(context.Background()))
+		assert.True(t, breaker.IsAvailable(context.Background()))
+	})
+
+	t.Run("force close, expect calls to pass and failures to open circuit once cleared", func(t *testing.T) {
+		breaker := newBreaker()
+
+		assert.Nil(t, breaker.ForceClose(context.Background(), 0))
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.EqualError(t, err, "some error")
+		assert.True(t, breaker.IsAvailable(context.Background()))
+
+		assert.Nil(t, breaker.ClearOverride(context.Background()))
+		assert.False(t, breaker.IsAvailable(context.Background()))
+	})
+
+	t.Run("disable, expect calls to pass and only be counted in stat", func(t *testing.T) {
+		breaker := newBreaker()
+
+		assert.Nil(t, breaker.Disable(context.Background(), 0))
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.EqualError(t, err, "some error")
+		assert.Equal(t, int64(1), breaker.Stat(context.Background()).Failure)
+
+		assert.Nil(t, breaker.ClearOverride(context.Background()))
+		assert.True(t, breaker.IsAvailable(context.Background()))
+	})
+
+	t.Run("storage does not support overrides, expect error", func(t *testing.T) {
+		breaker := circuitbreaker.NewCircuit(circuitbreaker.WithStorage(&mock.Storage{}))
+
+		assert.Equal(t, circuitbreaker.ErrOverrideNotSupported, breaker.ForceOpen(context.Background(), time.Minute))
+		assert.Equal(t, circuitbreaker.OverrideNone, breaker.Override(context.Background()))
+	})
+}
+
+func TestCircuitBreaker_Reset(t *testing.T) {
+	storage := circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(1), circuitbreaker.WithOpenWindow(time.Minute))
+	breaker := circuitbreaker.NewCircuit(circuitbreaker.WithStorage(storage))
+
+	breaker.Done(context.Background(), errors.New("some error"))
+	assert.True(t, breaker.Is(context.Background(), circuitbreaker.StateOpen))
+
+	assert.Nil(t, breaker.Reset(context.Background()))
+	assert.True(t, breaker.Is(context.Background(), circuitbreaker.StateClose))
+	assert.Equal(t, int64(1), breaker.StorageOptions().FailureRateThreshold)
+}
+
+// newMemoryCircuit create a circuit on a memory storage with storageOptions, it is close if storage fails.
+func newMemoryCircuit(storageOptions []circuitbreaker.StorageOption, options ...circuitbreaker.Option) *circuitbreaker.Circuit {
+	return circuitbreaker.NewCircuit(append([]circuitbreaker.Option{
+		circuitbreaker.WithStorage(circuitbreaker.NewMemoryStorage(storageOptions...)),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	}, options...)...)
+}
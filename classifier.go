@@ -0,0 +1,92 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+)
+
+// Outcome is how the result of a call is stored in circuit.
+type Outcome int64
+
+const (
+	// OutcomeFailure mean call is stored as a failure.
+	OutcomeFailure Outcome = iota
+
+	// OutcomeSuccess mean call is stored as a success.
+	OutcomeSuccess
+
+	// OutcomeIgnore mean call is not stored.
+	OutcomeIgnore
+)
+
+// ErrorClassifier decides about the outcome of a call that returned a non nil error.
+type ErrorClassifier func(err error) Outcome
+
+// StatusCoder is implemented by errors that carry the HTTP status code of a response.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// OnlyTheseErrorsClassifier only consider these errors as failure, other errors are success.
+func OnlyTheseErrorsClassifier(errs ...error) ErrorClassifier {
+	return func(err error) Outcome {
+		if OnlyTheseErrors(err, errs...) != nil {
+			return OutcomeFailure
+		}
+
+		return OutcomeSuccess
+	}
+}
+
+// IgnoreTheseErrorsClassifier ignore these errors, other errors are failure.
+func IgnoreTheseErrorsClassifier(errs ...error) ErrorClassifier {
+	return func(err error) Outcome {
+		if OnlyTheseErrors(err, errs...) != nil {
+			return OutcomeIgnore
+		}
+
+		return OutcomeFailure
+	}
+}
+
+// IgnoreCanceledClassifier ignore context.Canceled errors, as they are caused by caller not the service.
+func IgnoreCanceledClassifier() ErrorClassifier {
+	return IgnoreTheseErrorsClassifier(context.Canceled)
+}
+
+// HTTPStatusClassifier consider errors carrying one of these status codes as failure, and the other
+// status codes as success. if no code is passed, 5xx status codes are failure.
+// errors that do not carry a status code, like connection errors, are failure.
+func HTTPStatusClassifier(codes ...int) ErrorClassifier {
+	return func(err error) Outcome {
+		var coder StatusCoder
+		if !errors.As(err, &coder) {
+			return OutcomeFailure
+		}
+
+		if len(codes) == 0 && coder.StatusCode() >= 500 {
+			return OutcomeFailure
+		}
+
+		for _, code := range codes {
+			if coder.StatusCode() == code {
+				return OutcomeFailure
+			}
+		}
+
+		return OutcomeSuccess
+	}
+}
+
+// classify the result of a call, nil error is always success.
+func (s *Circuit) classify(err error) Outcome {
+	if err == nil {
+		return OutcomeSuccess
+	}
+
+	if s.ops.ErrorClassifier == nil {
+		return OutcomeFailure
+	}
+
+	return s.ops.ErrorClassifier(err)
+}
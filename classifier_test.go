@@ -0,0 +1,106 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+type statusError int
+
+func (s statusError) Error() string {
+	return fmt.Sprintf("status code %d", int(s))
+}
+
+func (s statusError) StatusCode() int {
+	return int(s)
+}
+
+func TestClassifiers(t *testing.T) {
+	err1 := errors.New("error1")
+	err2 := errors.New("error2")
+
+	testCases := []struct {
+		Name       string
+		Classifier circuitbreaker.ErrorClassifier
+		Error      error
+		Expected   circuitbreaker.Outcome
+	}{
+		{Name: "only these errors, matched", Classifier: circuitbreaker.OnlyTheseErrorsClassifier(err1), Error: fmt.Errorf("wrapped: %w", err1), Expected: circuitbreaker.OutcomeFailure},
+		{Name: "only these errors, not matched", Classifier: circuitbreaker.OnlyTheseErrorsClassifier(err1), Error: err2, Expected: circuitbreaker.OutcomeSuccess},
+		{Name: "ignore these errors, matched", Classifier: circuitbreaker.IgnoreTheseErrorsClassifier(err1), Error: err1, Expected: circuitbreaker.OutcomeIgnore},
+		{Name: "ignore these errors, not matched", Classifier: circuitbreaker.IgnoreTheseErrorsClassifier(err1), Error: err2, Expected: circuitbreaker.OutcomeFailure},
+		{Name: "ignore canceled", Classifier: circuitbreaker.IgnoreCanceledClassifier(), Error: context.Canceled, Expected: circuitbreaker.OutcomeIgnore},
+		{Name: "http status, default 5xx", Classifier: circuitbreaker.HTTPStatusClassifier(), Error: statusError(503), Expected: circuitbreaker.OutcomeFailure},
+		{Name: "http status, default 4xx", Classifier: circuitbreaker.HTTPStatusClassifier(), Error: statusError(404), Expected: circuitbreaker.OutcomeSuccess},
+		{Name: "http status, matched code", Classifier: circuitbreaker.HTTPStatusClassifier(429), Error: fmt.Errorf("wrapped: %w", statusError(429)), Expected: circuitbreaker.OutcomeFailure},
+		{Name: "http status, not matched code", Classifier: circuitbreaker.HTTPStatusClassifier(429), Error: statusError(500), Expected: circuitbreaker.OutcomeSuccess},
+		{Name: "http status, no status code", Classifier: circuitbreaker.HTTPStatusClassifier(), Error: err1, Expected: circuitbreaker.OutcomeFailure},
+	}
+
+	for _, item := range testCases {
+		item := item
+		t.Run(item.Name, func(t *testing.T) {
+			assert.Equal(t, item.Expected, item.Classifier(item.Error))
+		})
+	}
+}
+
+func TestCircuitBreaker_ErrorClassifier(t *testing.T) {
+	ignoredErr := errors.New("ignored")
+	successErr := errors.New("success")
+
+	storage := &mock.Storage{}
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		circuitbreaker.WithErrorClassifier(func(err error) circuitbreaker.Outcome {
+			switch {
+			case errors.Is(err, ignoredErr):
+				return circuitbreaker.OutcomeIgnore
+			case errors.Is(err, successErr):
+				return circuitbreaker.OutcomeSuccess
+			}
+
+			return circuitbreaker.OutcomeFailure
+		}),
+	)
+
+	t.Run("error is ignored, expect to not store anything", func(t *testing.T) {
+		breaker.Done(context.Background(), ignoredErr)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("error is success, expect to store success", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Twice()
+
+		_, err := breaker.Do(context.Background(), func() (interface{}, error) { return nil, successErr })
+		assert.Equal(t, successErr, err)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("error is failure, expect to store failure", func(t *testing.T) {
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+
+		breaker.Done(context.Background(), errors.New("some error"))
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("expect stat to count only stored calls", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+
+		stat := breaker.Stat(context.Background())
+		assert.Equal(t, circuitbreaker.Stat{State: circuitbreaker.StateClose, Failure: 1, Success: 1}, stat)
+
+		storage.AssertExpectations(t)
+	})
+}
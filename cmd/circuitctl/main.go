@@ -0,0 +1,172 @@
+// Command circuitctl inspects and controls circuits stored in redis.
+//
+//	circuitctl [flags] list
+//	circuitctl [flags] status <service>
+//	circuitctl [flags] reset <service>
+//	circuitctl [flags] force-open|force-close|disable [-ttl duration] <service>
+//	circuitctl [flags] clear <service>
+//
+// state is evaluated with the threshold flags, so they should be the same as the storage options of services.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mrsoftware/circuitbreaker"
+)
+
+var errUsage = errors.New("usage: circuitctl [flags] list|status|reset|force-open|force-close|disable|clear [service]")
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// config is the global flags.
+type config struct {
+	addrs            string
+	username         string
+	password         string
+	db               int
+	json             bool
+	failureThreshold int64
+	successThreshold int64
+	slowThreshold    int64
+	openWindow       time.Duration
+	halfOpenWindow   time.Duration
+	windowed         bool
+}
+
+func (c *config) storageOptions(service string) []circuitbreaker.StorageOption {
+	options := []circuitbreaker.StorageOption{
+		circuitbreaker.WithServiceName(service),
+		circuitbreaker.WithFailureRateThreshold(c.failureThreshold),
+		circuitbreaker.WithSuccessRateThreshold(c.successThreshold),
+		circuitbreaker.WithSlowCallRateThreshold(c.slowThreshold),
+		circuitbreaker.WithOpenWindow(c.openWindow),
+		circuitbreaker.WithHalfOpenWindow(c.halfOpenWindow),
+	}
+
+	// the window size is not used to evaluate state, only the window mode.
+	if c.windowed {
+		options = append(options, circuitbreaker.WithCountBasedSlidingWindow(1))
+	}
+
+	return options
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var cfg config
+
+	flags := flag.NewFlagSet("circuitctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&cfg.addrs, "addr", "localhost:6379", "comma separated redis addresses, more than one is a cluster")
+	flags.StringVar(&cfg.username, "username", "", "redis username")
+	flags.StringVar(&cfg.password, "password", os.Getenv("REDIS_PASSWORD"), "redis password, default is $REDIS_PASSWORD")
+	flags.IntVar(&cfg.db, "db", 0, "redis database")
+	flags.BoolVar(&cfg.json, "json", false, "print reports as json")
+	flags.Int64Var(&cfg.failureThreshold, "failure-threshold", circuitbreaker.DefaultFailureRateThreshold, "failure rate threshold of services")
+	flags.Int64Var(&cfg.successThreshold, "success-threshold", circuitbreaker.DefaultSuccessRateThreshold, "success rate threshold of services")
+	flags.Int64Var(&cfg.slowThreshold, "slow-threshold", 0, "slow call rate threshold of services")
+	flags.DurationVar(&cfg.openWindow, "open-window", circuitbreaker.DefaultOpenWindow, "open window of services")
+	flags.DurationVar(&cfg.halfOpenWindow, "half-open-window", circuitbreaker.DefaultHalfOpenWindow, "half open window of services")
+	flags.BoolVar(&cfg.windowed, "windowed", false, "services use a sliding window")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    strings.Split(cfg.addrs, ","),
+		Username: cfg.username,
+		Password: cfg.password,
+		DB:       cfg.db,
+	})
+	defer client.Close()
+
+	return execute(ctx, client, &cfg, flags.Args(), stdout, stderr)
+}
+
+func execute(ctx context.Context, client redis.UniversalClient, cfg *config, args []string, stdout, stderr io.Writer) error {
+	command, args := args[0], args[1:]
+
+	if command == "list" {
+		services, err := listServices(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		reports := make([]report, 0, len(services))
+
+		for _, service := range services {
+			r, err := readReport(ctx, client, cfg, service)
+			if err != nil {
+				return err
+			}
+
+			reports = append(reports, r)
+		}
+
+		return printReports(stdout, cfg.json, reports)
+	}
+
+	var ttl time.Duration
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.DurationVar(&ttl, "ttl", 0, "ttl of the override, 0 is without expiry")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	service := flags.Arg(0)
+	storage := circuitbreaker.NewRedisStorage(client, cfg.storageOptions(service)...)
+
+	var err error
+
+	switch command {
+	case "status":
+	case "reset":
+		err = storage.Reset(ctx)
+	case "force-open":
+		err = storage.SetOverride(ctx, circuitbreaker.OverrideForceOpen, ttl)
+	case "force-close":
+		err = storage.SetOverride(ctx, circuitbreaker.OverrideForceClose, ttl)
+	case "disable":
+		err = storage.SetOverride(ctx, circuitbreaker.OverrideDisable, ttl)
+	case "clear":
+		err = storage.SetOverride(ctx, circuitbreaker.OverrideNone, 0)
+	default:
+		return errUsage
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", command, service, err)
+	}
+
+	r, err := readReport(ctx, client, cfg, service)
+	if err != nil {
+		return err
+	}
+
+	return printReport(stdout, cfg.json, r)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	storageOptions := []circuitbreaker.StorageOption{
+		circuitbreaker.WithFailureRateThreshold(2),
+		circuitbreaker.WithOpenWindow(time.Minute),
+		circuitbreaker.WithHalfOpenWindow(10 * time.Second),
+	}
+
+	users := circuitbreaker.NewRedisStorage(client, append(storageOptions, circuitbreaker.WithServiceName("users"))...)
+	orders := circuitbreaker.NewRedisStorage(client, append(storageOptions, circuitbreaker.WithServiceName("orders"))...)
+
+	assert.Nil(t, users.Failure(context.Background(), 2))
+	assert.Nil(t, orders.Failure(context.Background(), 1))
+
+	circuitctl := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+
+		args = append([]string{"-addr", server.Addr(), "-failure-threshold", "2", "-open-window", "1m", "-half-open-window", "10s"}, args...)
+		err := run(context.Background(), args, &stdout, io.Discard)
+
+		return stdout.String(), err
+	}
+
+	t.Run("list, expect reports of all services", func(t *testing.T) {
+		out, err := circuitctl("-json", "list")
+		assert.Nil(t, err)
+
+		var reports []report
+		assert.Nil(t, json.Unmarshal([]byte(out), &reports))
+		assert.Equal(t, []report{
+			{Service: "orders", State: "Close", Override: "None", Failures: 1, TTLMs: 60000},
+			{Service: "users", State: "Open", Override: "None", Failures: 2, TTLMs: 60000, OpenRemainingMs: 50000},
+		}, reports)
+	})
+
+	t.Run("status, expect human report of service", func(t *testing.T) {
+		out, err := circuitctl("status", "users")
+		assert.Nil(t, err)
+		assert.Contains(t, out, "SERVICE")
+		assert.Regexp(t, `users\s+Open\s+None\s+2\s+0\s+0\s+0\s+50s\s+1m0s`, out)
+	})
+
+	t.Run("force open with ttl, expect override to be set and expire", func(t *testing.T) {
+		out, err := circuitctl("-json", "force-open", "-ttl", "5m", "orders")
+		assert.Nil(t, err)
+
+		var r report
+		assert.Nil(t, json.Unmarshal([]byte(out), &r))
+		assert.Equal(t, "Open", r.State)
+		assert.Equal(t, "ForceOpen", r.Override)
+
+		server.FastForward(5 * time.Minute)
+
+		state, err := orders.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+	})
+
+	t.Run("disable and clear, expect override to be removed", func(t *testing.T) {
+		_, err := circuitctl("disable", "users")
+		assert.Nil(t, err)
+
+		state, err := users.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		_, err = circuitctl("clear", "users")
+		assert.Nil(t, err)
+
+		override, err := users.GetOverride(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.OverrideNone, override)
+	})
+
+	t.Run("reset, expect service keys to be deleted", func(t *testing.T) {
+		assert.Nil(t, users.Failure(context.Background(), 2))
+
+		_, err := circuitctl("reset", "users")
+		assert.Nil(t, err)
+		assert.False(t, server.Exists(circuitbreaker.StorageKey("users")))
+	})
+
+	t.Run("invalid commands, expect usage error", func(t *testing.T) {
+		_, err := circuitctl()
+		assert.Equal(t, errUsage, err)
+
+		_, err = circuitctl("unknown", "users")
+		assert.Equal(t, errUsage, err)
+
+		_, err = circuitctl("reset")
+		assert.Equal(t, errUsage, err)
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mrsoftware/circuitbreaker"
+)
+
+// report is the state of a service decoded from its redis keys.
+type report struct {
+	Service  string `json:"service"`
+	State    string `json:"state"`
+	Override string `json:"override"`
+	Failures int64  `json:"failures"`
+	Slow     int64  `json:"slow"`
+	Success  int64  `json:"success"`
+	Trips    int64  `json:"trips"`
+	// TTLMs is the ttl of service key, that is open and half open window, in milliseconds.
+	TTLMs int64 `json:"ttlMs"`
+	// OpenRemainingMs is the time left until the circuit moves to half open state, in milliseconds.
+	OpenRemainingMs int64 `json:"openRemainingMs"`
+}
+
+// listServices returns services that have keys in redis, sorted.
+func listServices(ctx context.Context, client redis.UniversalClient) ([]string, error) {
+	var (
+		lock     sync.Mutex
+		services = make(map[string]struct{})
+	)
+
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, circuitbreaker.StorageKeyPattern, 100).Iterator()
+
+		for iter.Next(ctx) {
+			if service, ok := circuitbreaker.ServiceOfKey(iter.Val()); ok {
+				lock.Lock()
+				services[service] = struct{}{}
+				lock.Unlock()
+			}
+		}
+
+		return iter.Err()
+	}
+
+	var err error
+
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error { return scan(ctx, node) })
+	} else {
+		err = scan(ctx, client)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("scanning keys: %w", err)
+	}
+
+	list := make([]string, 0, len(services))
+
+	for service := range services {
+		list = append(list, service)
+	}
+
+	sort.Strings(list)
+
+	return list, nil
+}
+
+func readReport(ctx context.Context, client redis.UniversalClient, cfg *config, service string) (report, error) {
+	storage := circuitbreaker.NewRedisStorage(client, cfg.storageOptions(service)...)
+	key := circuitbreaker.StorageKey(service)
+
+	fields, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return report{}, fmt.Errorf("reading %s: %w", service, err)
+	}
+
+	ttl, err := client.PTTL(ctx, key).Result()
+	if err != nil {
+		return report{}, fmt.Errorf("reading ttl of %s: %w", service, err)
+	}
+
+	state, err := storage.GetState(ctx)
+	if err != nil {
+		return report{}, fmt.Errorf("reading state of %s: %w", service, err)
+	}
+
+	override, err := storage.GetOverride(ctx)
+	if err != nil {
+		return report{}, fmt.Errorf("reading override of %s: %w", service, err)
+	}
+
+	remaining, err := storage.OpenRemaining(ctx)
+	if err != nil {
+		return report{}, fmt.Errorf("reading open remaining time of %s: %w", service, err)
+	}
+
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return report{
+		Service:         service,
+		State:           circuitbreaker.GetStateText(state),
+		Override:        circuitbreaker.GetOverrideText(override),
+		Failures:        intField(fields, "failures"),
+		Slow:            intField(fields, "slow"),
+		Success:         intField(fields, "success"),
+		Trips:           intField(fields, "trips"),
+		TTLMs:           ttl.Milliseconds(),
+		OpenRemainingMs: remaining.Milliseconds(),
+	}, nil
+}
+
+func intField(fields map[string]string, name string) int64 {
+	value, _ := strconv.ParseInt(fields[name], 10, 64)
+
+	return value
+}
+
+func printReport(w io.Writer, asJSON bool, r report) error {
+	if asJSON {
+		return encodeJSON(w, r)
+	}
+
+	return printReports(w, false, []report{r})
+}
+
+func printReports(w io.Writer, asJSON bool, reports []report) error {
+	if asJSON {
+		return encodeJSON(w, reports)
+	}
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SERVICE\tSTATE\tOVERRIDE\tFAILURES\tSLOW\tSUCCESS\tTRIPS\tOPEN REMAINING\tTTL")
+
+	for _, r := range reports {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			r.Service, r.State, r.Override, r.Failures, r.Slow, r.Success, r.Trips,
+			time.Duration(r.OpenRemainingMs)*time.Millisecond, time.Duration(r.TTLMs)*time.Millisecond,
+		)
+	}
+
+	return writer.Flush()
+}
+
+func encodeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
@@ -5,6 +5,7 @@ import (
 )
 
 // OnlyTheseErrors used when you want to consider only these errors.
+// to apply it to all calls of a circuit, use OnlyTheseErrorsClassifier.
 func OnlyTheseErrors(err error, errs ...error) error {
 	if err == nil {
 		return nil
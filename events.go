@@ -0,0 +1,118 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// StateChange is the event of circuit moving from a state to another.
+type StateChange struct {
+	From State
+	To   State
+	Stat Stat
+}
+
+// OnStateChange is callback that is called on each state change of circuit.
+type OnStateChange func(from, to State, stat Stat)
+
+// subscribers of circuit state changes.
+type subscribers struct {
+	lock     sync.RWMutex
+	lastID   int
+	channels map[int]chan StateChange
+}
+
+func (s *subscribers) add(buffer int) (int, chan StateChange) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.channels == nil {
+		s.channels = make(map[int]chan StateChange)
+	}
+
+	s.lastID++
+	s.channels[s.lastID] = make(chan StateChange, buffer)
+
+	return s.lastID, s.channels[s.lastID]
+}
+
+func (s *subscribers) remove(id int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if channel, ok := s.channels[id]; ok {
+		delete(s.channels, id)
+		close(channel)
+	}
+}
+
+func (s *subscribers) len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.channels)
+}
+
+// publish event to all subscribers, event is dropped for subscribers that their buffer is full.
+func (s *subscribers) publish(event StateChange) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, channel := range s.channels {
+		select {
+		case channel <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe to state changes of circuit, events are sent to returned channel that has buffer size,
+// and they are dropped if the channel is full. call the returned function to unsubscribe.
+func (s *Circuit) Subscribe(buffer int) (<-chan StateChange, func()) {
+	id, channel := s.subscribers.add(buffer)
+
+	var once sync.Once
+
+	return channel, func() { once.Do(func() { s.subscribers.remove(id) }) }
+}
+
+// watched reports if anyone is interested in state changes.
+func (s *Circuit) watched() bool {
+	return s.ops.OnStateChange != nil || s.subscribers.len() > 0
+}
+
+// observe the state that is read from storage, and notify if it differs from last observed state.
+// state changes are only observed when circuit reads the state, so transitions made by other
+// instances sharing the storage are notified on the next read.
+func (s *Circuit) observe(state State) {
+	from := State(atomic.SwapInt64(&s.state, int64(state)))
+	if from == state || from == StateUnknown || state == StateUnknown {
+		return
+	}
+
+	stat := s.stat(state)
+
+	if s.ops.OnStateChange != nil {
+		s.ops.OnStateChange(from, state, stat)
+	}
+
+	s.subscribers.publish(StateChange{From: from, To: state, Stat: stat})
+}
+
+// refresh read the state from storage after storing a call result, if anyone is interested in state changes.
+func (s *Circuit) refresh(ctx context.Context) {
+	if !s.watched() {
+		return
+	}
+
+	state, err := s.ops.Storage.GetState(ctx)
+	if err != nil {
+		s.ops.Logger.Error(fmt.Errorf("refreshing state: %w", err))
+
+		return
+	}
+
+	s.observe(state)
+}
@@ -0,0 +1,96 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_OnStateChange(t *testing.T) {
+	t.Run("expect callback to be called once for each state change", func(t *testing.T) {
+		var changes []circuitbreaker.StateChange
+
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(1),
+			circuitbreaker.WithSuccessRateThreshold(1),
+			circuitbreaker.WithOpenWindow(time.Minute),
+		)
+
+		breaker := circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+			circuitbreaker.WithOnStateChange(func(from, to circuitbreaker.State, stat circuitbreaker.Stat) {
+				changes = append(changes, circuitbreaker.StateChange{From: from, To: to, Stat: stat})
+			}),
+		)
+
+		assert.True(t, breaker.IsAvailable(context.Background()))
+
+		breaker.Done(context.Background(), errors.New("some error"))
+		assert.False(t, breaker.IsAvailable(context.Background()))
+		assert.False(t, breaker.IsAvailable(context.Background()))
+
+		breaker.Done(context.Background(), nil)
+		assert.True(t, breaker.IsAvailable(context.Background()))
+
+		assert.Equal(t, []circuitbreaker.StateChange{
+			{
+				From: circuitbreaker.StateClose,
+				To:   circuitbreaker.StateOpen,
+				Stat: circuitbreaker.Stat{State: circuitbreaker.StateOpen, Failure: 1},
+			},
+			{
+				From: circuitbreaker.StateOpen,
+				To:   circuitbreaker.StateClose,
+				Stat: circuitbreaker.Stat{State: circuitbreaker.StateClose, Failure: 1, Success: 1},
+			},
+		}, changes)
+	})
+}
+
+func TestCircuitBreaker_Subscribe(t *testing.T) {
+	storage := circuitbreaker.NewMemoryStorage(
+		circuitbreaker.WithFailureRateThreshold(1),
+		circuitbreaker.WithOpenWindow(time.Minute),
+	)
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)
+
+	t.Run("state is changed by another instance sharing the storage, expect to be notified on next read", func(t *testing.T) {
+		events, unsubscribe := breaker.Subscribe(1)
+		defer unsubscribe()
+
+		assert.True(t, breaker.IsAvailable(context.Background()))
+
+		another := circuitbreaker.NewCircuit(circuitbreaker.WithStorage(storage))
+		another.Done(context.Background(), errors.New("some error"))
+
+		select {
+		case <-events:
+			t.Fatal("state change is not observed yet")
+		default:
+		}
+
+		assert.Equal(t, circuitbreaker.StateOpen, breaker.GetState(context.Background()))
+
+		event := <-events
+		assert.Equal(t, circuitbreaker.StateClose, event.From)
+		assert.Equal(t, circuitbreaker.StateOpen, event.To)
+	})
+
+	t.Run("expect channel to be closed after unsubscribe", func(t *testing.T) {
+		events, unsubscribe := breaker.Subscribe(1)
+		unsubscribe()
+		unsubscribe()
+
+		_, ok := <-events
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/mrsoftware/circuitbreaker"
+)
+
+var cb circuitbreaker.Manager
+
+func main() {
+	cb = circuitbreaker.NewCircuit(circuitbreaker.WithDefaultOptions())
+
+	res, err := Get(context.Background(), "https://google.com")
+	if err != nil {
+		log.Println(err)
+
+		return
+	}
+
+	fmt.Println("response: ", string(res))
+}
+
+func Get(ctx context.Context, url string) ([]byte, error) {
+	// like `Do`, but the result is typed and there is no need to type assert it.
+	return circuitbreaker.Execute(ctx, cb, func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			return nil, errors.New("response status code is not 200")
+		}
+
+		return io.ReadAll(resp.Body)
+	})
+}
@@ -0,0 +1,57 @@
+package circuitbreaker
+
+import (
+	"context"
+)
+
+// Func is type safe callable that Execute accept.
+type Func[T any] func(ctx context.Context) (T, error)
+
+// Execute call fn through the manager like DoContext, but its result is typed.
+// if the circuit is open, zero value of T and ErrIsOpen are returned.
+// if manager is not a ContextManager, fn is called through Do and its ctx is not canceled when the call times out.
+func Execute[T any](ctx context.Context, manager Manager, fn Func[T]) (T, error) {
+	call := func(ctx context.Context) (interface{}, error) { return fn(ctx) }
+
+	var (
+		res interface{}
+		err error
+	)
+
+	if m, ok := manager.(ContextManager); ok {
+		res, err = m.DoContext(ctx, call)
+	} else {
+		res, err = manager.Do(ctx, func() (interface{}, error) { return call(ctx) })
+	}
+
+	result, _ := res.(T)
+
+	return result, err
+}
+
+// ExecuteWithFallback call fn through the manager like DoContextWithFallback, but its result is typed.
+// if manager is not a ContextManager, fn is called through Do and fallback is called if it is failed.
+func ExecuteWithFallback[T any](ctx context.Context, manager Manager, fn Func[T], fallback func(ctx context.Context, err error) (T, error)) (T, error) {
+	if fallback == nil {
+		return Execute(ctx, manager, fn)
+	}
+
+	m, ok := manager.(ContextManager)
+	if !ok {
+		result, err := Execute(ctx, manager, fn)
+		if err == nil {
+			return result, nil
+		}
+
+		return fallback(ctx, err)
+	}
+
+	res, err := m.DoContextWithFallback(ctx,
+		func(ctx context.Context) (interface{}, error) { return fn(ctx) },
+		func(ctx context.Context, err error) (interface{}, error) { return fallback(ctx, err) },
+	)
+
+	result, _ := res.(T)
+
+	return result, err
+}
@@ -0,0 +1,149 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute(t *testing.T) {
+	storage := &mock.Storage{}
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)
+
+	t.Run("circuit is close/available, expect to get typed result", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Twice()
+
+		response, err := circuitbreaker.Execute(context.Background(), breaker, func(ctx context.Context) ([]byte, error) {
+			return []byte("response"), nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("response"), response)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("circuit is close/available, but service call is failed, expect to get result and error", func(t *testing.T) {
+		expectedErr := errors.New("service faild")
+
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+
+		response, err := circuitbreaker.Execute(context.Background(), breaker, func(ctx context.Context) (int, error) {
+			return 10, expectedErr
+		})
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, 10, response)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("circuit is not available, expect to get zero value and ErrIsOpen Error", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateOpen, nil).Once()
+
+		response, err := circuitbreaker.Execute(context.Background(), breaker, func(ctx context.Context) (*int, error) {
+			return nil, nil
+		})
+		assert.Nil(t, response)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestExecuteWithFallback(t *testing.T) {
+	storage := &mock.Storage{}
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)
+
+	t.Run("circuit is not available, expect to get typed result of fallback", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateOpen, nil).Once()
+
+		response, err := circuitbreaker.ExecuteWithFallback(context.Background(), breaker,
+			func(ctx context.Context) ([]byte, error) { return []byte("response"), nil },
+			func(ctx context.Context, err error) ([]byte, error) { return []byte("stale"), nil },
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("stale"), response)
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestExecuteWithFallback_CallTimeout(t *testing.T) {
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(10))),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		circuitbreaker.WithCallTimeout(10*time.Millisecond),
+	)
+
+	canceled := make(chan error, 1)
+
+	response, err := circuitbreaker.ExecuteWithFallback(context.Background(), breaker,
+		func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+
+			return "", ctx.Err()
+		},
+		func(ctx context.Context, err error) (string, error) {
+			assert.Equal(t, circuitbreaker.ErrTimeout, err)
+
+			return "stale", nil
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "stale", response)
+
+	// ctx of fn is canceled when the call timed out.
+	select {
+	case err := <-canceled:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		t.Fatal("ctx of fn is not canceled")
+	}
+}
+
+func TestExecute_Manager(t *testing.T) {
+	// only the methods of Manager, like an implementation that does not support DoContext.
+	manager := struct{ circuitbreaker.Manager }{circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(10))),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)}
+
+	t.Run("manager is not a ContextManager, expect fn to be called through Do", func(t *testing.T) {
+		response, err := circuitbreaker.Execute(context.Background(), manager, func(ctx context.Context) (string, error) {
+			return "response", nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "response", response)
+		assert.Equal(t, int64(1), manager.Stat(context.Background()).Success)
+	})
+
+	t.Run("manager is not a ContextManager and fn is failed, expect to get typed result of fallback", func(t *testing.T) {
+		expectedErr := errors.New("service faild")
+
+		response, err := circuitbreaker.ExecuteWithFallback(context.Background(), manager,
+			func(ctx context.Context) (string, error) { return "", expectedErr },
+			func(ctx context.Context, err error) (string, error) {
+				assert.Equal(t, expectedErr, err)
+
+				return "stale", nil
+			},
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "stale", response)
+		assert.Equal(t, int64(1), manager.Stat(context.Background()).Failure)
+	})
+}
@@ -0,0 +1,371 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultStorageErrorThreshold is number of storage errors that FailoverStorage stops calling it after.
+	DefaultStorageErrorThreshold int64 = 3
+
+	// DefaultStorageRetryInterval is how long FailoverStorage waits before calling a failed storage again.
+	DefaultStorageRetryInterval = time.Second * 10
+)
+
+var (
+	_ Storage           = &FailoverStorage{}
+	_ WindowedStorage   = &FailoverStorage{}
+	_ HalfOpenLimiter   = &FailoverStorage{}
+	_ SlowCallStorage   = &FailoverStorage{}
+	_ OpenWindowStorage = &FailoverStorage{}
+	_ DegradedStorage   = &FailoverStorage{}
+	_ StateNotifier     = &FailoverStorage{}
+	_ OverrideStorage   = &FailoverStorage{}
+	_ OptionsStorage    = &FailoverStorage{}
+)
+
+// FailoverOptions is FailoverStorage options.
+type FailoverOptions struct {
+	// ErrorThreshold is number of primary storage errors, each in RetryInterval of the previous one, to stop calling it
+	ErrorThreshold int64
+	// RetryInterval is how long primary storage is not called after it reached ErrorThreshold
+	RetryInterval time.Duration
+	Logger        Logger
+}
+
+// FailoverOption configures the FailoverStorage.
+type FailoverOption func(*FailoverOptions)
+
+// WithStorageErrorThreshold sets number of primary storage errors to stop calling it.
+func WithStorageErrorThreshold(threshold int64) FailoverOption {
+	return func(o *FailoverOptions) {
+		o.ErrorThreshold = threshold
+	}
+}
+
+// WithStorageRetryInterval sets how long primary storage is not called after it reached the error threshold.
+func WithStorageRetryInterval(interval time.Duration) FailoverOption {
+	return func(o *FailoverOptions) {
+		o.RetryInterval = interval
+	}
+}
+
+// WithFailoverLogger sets the logger of primary storage errors.
+func WithFailoverLogger(logger Logger) FailoverOption {
+	return func(o *FailoverOptions) {
+		o.Logger = logger
+	}
+}
+
+// FailoverStorage is a Storage decorator that falls back to a local shadow storage when the primary storage fails,
+// it is useful in front of a distributed storage like RedisStorage, and is concurrent safe.
+// while primary is failing, storage is degraded, call results are stored in shadow and kept,
+// and when primary is back, they are written to it and shadow is reset.
+// primary storage is itself guarded by a circuit, so a dead primary is not called on each check.
+type FailoverStorage struct {
+	primary Storage
+	shadow  Storage
+	options FailoverOptions
+	breaker *Circuit
+
+	degraded atomic.Bool
+	pending  pendingResults
+}
+
+// NewFailoverStorage create new instance of FailoverStorage, shadow should have the same options as primary,
+// like a MemoryStorage of the same service.
+func NewFailoverStorage(primary, shadow Storage, options ...FailoverOption) *FailoverStorage {
+	storage := FailoverStorage{
+		primary: primary,
+		shadow:  shadow,
+		options: FailoverOptions{
+			ErrorThreshold: DefaultStorageErrorThreshold,
+			RetryInterval:  DefaultStorageRetryInterval,
+			Logger:         NewIOLogger(os.Stdout, OutPutTypeSimple),
+		},
+	}
+
+	for _, op := range options {
+		op(&storage.options)
+	}
+
+	storage.breaker = NewCircuit(
+		WithStorage(NewMemoryStorage(
+			WithFailureRateThreshold(storage.options.ErrorThreshold),
+			WithOpenWindow(storage.options.RetryInterval),
+		)),
+		WithFallbackState(StateClose),
+		WithLogger(storage.options.Logger),
+	)
+
+	return &storage
+}
+
+// Degraded reports if primary storage is failing and shadow storage is used.
+func (f *FailoverStorage) Degraded() bool {
+	return f.degraded.Load()
+}
+
+// Failure is responsible to store failures.
+func (f *FailoverStorage) Failure(ctx context.Context, delta int64) error {
+	return f.store(ctx, &f.pending.failures, delta, func(storage Storage) error {
+		return storage.Failure(ctx, delta)
+	})
+}
+
+// Slow is responsible to store slow calls.
+func (f *FailoverStorage) Slow(ctx context.Context, delta int64) error {
+	return f.store(ctx, &f.pending.slow, delta, func(storage Storage) error {
+		return slowCall(ctx, storage, delta)
+	})
+}
+
+// Success is responsible to store success.
+func (f *FailoverStorage) Success(ctx context.Context, delta int64) error {
+	return f.store(ctx, &f.pending.success, delta, func(storage Storage) error {
+		return storage.Success(ctx, delta)
+	})
+}
+
+// store the call result in primary, or in shadow and pending results if storage is degraded.
+func (f *FailoverStorage) store(ctx context.Context, pending *atomic.Int64, delta int64, fn func(storage Storage) error) error {
+	if !f.Degraded() {
+		_, err := f.breaker.Do(ctx, func() (interface{}, error) { return nil, fn(f.primary) })
+		if err == nil {
+			return nil
+		}
+
+		if !f.failed(ctx, err) {
+			return err
+		}
+	}
+
+	pending.Add(delta)
+
+	return fn(f.shadow)
+}
+
+// GetState of primary, or of shadow if storage is degraded.
+func (f *FailoverStorage) GetState(ctx context.Context) (State, error) {
+	if f.Degraded() {
+		if state, ok := f.reconcile(ctx); ok {
+			return state, nil
+		}
+
+		return f.shadow.GetState(ctx)
+	}
+
+	state, err := f.breaker.Do(ctx, func() (interface{}, error) { return f.primary.GetState(ctx) })
+	if err != nil {
+		if !f.failed(ctx, err) {
+			return StateClose, err
+		}
+
+		return f.shadow.GetState(ctx)
+	}
+
+	return state.(State), nil
+}
+
+// Reset the state of both primary and shadow, pending results are dropped.
+func (f *FailoverStorage) Reset(ctx context.Context) error {
+	f.pending.reset()
+
+	_, err := f.breaker.Do(ctx, func() (interface{}, error) { return nil, f.primary.Reset(ctx) })
+	if err != nil && !f.failed(ctx, err) {
+		return err
+	}
+
+	return f.shadow.Reset(ctx)
+}
+
+// StorageOptions of primary storage, they are zero if it does not expose them.
+func (f *FailoverStorage) StorageOptions() StorageOptions {
+	storage, ok := f.primary.(OptionsStorage)
+	if !ok {
+		return StorageOptions{}
+	}
+
+	return storage.StorageOptions()
+}
+
+// Windowed reports if primary storage use a sliding window.
+func (f *FailoverStorage) Windowed() bool {
+	storage, ok := f.primary.(WindowedStorage)
+
+	return ok && storage.Windowed()
+}
+
+// AcquireHalfOpen reports if one more trial call is permitted, it is always permitted if storage does not limit them.
+func (f *FailoverStorage) AcquireHalfOpen(ctx context.Context) (bool, error) {
+	acquire := func(storage Storage) (bool, error) {
+		if limiter, ok := storage.(HalfOpenLimiter); ok {
+			return limiter.AcquireHalfOpen(ctx)
+		}
+
+		return true, nil
+	}
+
+	if !f.Degraded() {
+		acquired, err := f.breaker.Do(ctx, func() (interface{}, error) { return acquire(f.primary) })
+		if err == nil {
+			return acquired.(bool), nil
+		}
+
+		if !f.failed(ctx, err) {
+			return false, err
+		}
+	}
+
+	return acquire(f.shadow)
+}
+
+// OpenRemaining is the time left until the circuit moves to half open state, it is zero if storage does not know it.
+func (f *FailoverStorage) OpenRemaining(ctx context.Context) (time.Duration, error) {
+	remaining := func(storage Storage) (time.Duration, error) {
+		if s, ok := storage.(OpenWindowStorage); ok {
+			return s.OpenRemaining(ctx)
+		}
+
+		return 0, nil
+	}
+
+	if !f.Degraded() {
+		duration, err := f.breaker.Do(ctx, func() (interface{}, error) { return remaining(f.primary) })
+		if err == nil {
+			return duration.(time.Duration), nil
+		}
+
+		if !f.failed(ctx, err) {
+			return 0, err
+		}
+	}
+
+	return remaining(f.shadow)
+}
+
+// SetOverride of primary and shadow storage, so it is still applied while degraded.
+// if primary storage fails, the override is only applied on shadow storage and the error is returned.
+func (f *FailoverStorage) SetOverride(ctx context.Context, override Override, ttl time.Duration) error {
+	primary, ok := f.primary.(OverrideStorage)
+	if !ok {
+		return ErrOverrideNotSupported
+	}
+
+	if shadow, ok := f.shadow.(OverrideStorage); ok {
+		if err := shadow.SetOverride(ctx, override, ttl); err != nil {
+			return err
+		}
+	}
+
+	_, err := f.breaker.Do(ctx, func() (interface{}, error) { return nil, primary.SetOverride(ctx, override, ttl) })
+	if err != nil {
+		f.failed(ctx, err)
+	}
+
+	return err
+}
+
+// GetOverride of primary storage, or shadow storage while degraded.
+func (f *FailoverStorage) GetOverride(ctx context.Context) (Override, error) {
+	get := func(storage Storage) (Override, error) {
+		if s, ok := storage.(OverrideStorage); ok {
+			return s.GetOverride(ctx)
+		}
+
+		return OverrideNone, nil
+	}
+
+	if !f.Degraded() {
+		override, err := f.breaker.Do(ctx, func() (interface{}, error) { return get(f.primary) })
+		if err == nil {
+			return override.(Override), nil
+		}
+
+		if !f.failed(ctx, err) {
+			return OverrideNone, err
+		}
+	}
+
+	return get(f.shadow)
+}
+
+// NotifyState calls fn with the new state on each change that primary storage notifies.
+func (f *FailoverStorage) NotifyState(fn func(state State)) func() {
+	notifier, ok := f.primary.(StateNotifier)
+	if !ok {
+		return func() {}
+	}
+
+	return notifier.NotifyState(fn)
+}
+
+// Close primary and shadow storage if they need to be closed.
+func (f *FailoverStorage) Close() error {
+	var err error
+
+	for _, storage := range []Storage{f.primary, f.shadow} {
+		if closer, ok := storage.(io.Closer); ok {
+			if closeErr := closer.Close(); closeErr != nil {
+				err = closeErr
+			}
+		}
+	}
+
+	return err
+}
+
+// failed degrades storage because of err of primary storage, and reports if it is degraded.
+// if the caller gave up, the error does not say anything about the primary, so storage is not degraded.
+func (f *FailoverStorage) failed(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if !f.degraded.Swap(true) {
+		f.options.Logger.Error(fmt.Errorf("primary storage failed, falling back to shadow storage: %w", err))
+	}
+
+	return true
+}
+
+// reconcile writes pending results to primary storage and reads its state, if it is back.
+// it reports if storage is not degraded anymore, the primary is always called so a dead one is not taken as back.
+func (f *FailoverStorage) reconcile(ctx context.Context) (State, bool) {
+	state, err := f.breaker.Do(ctx, func() (interface{}, error) {
+		if _, err := f.pending.flush(ctx, f.primary); err != nil {
+			return nil, err
+		}
+
+		return f.primary.GetState(ctx)
+	})
+	if err != nil {
+		return StateClose, false
+	}
+
+	if !f.degraded.Swap(false) {
+		return state.(State), true
+	}
+
+	if err := f.shadow.Reset(ctx); err != nil {
+		f.options.Logger.Error(fmt.Errorf("resetting shadow storage: %w", err))
+	}
+
+	f.options.Logger.Info("primary storage is back, pending results are written to it")
+
+	return state.(State), true
+}
+
+// slowCall store slow calls in storage, or failures if storage does not keep slow calls apart.
+func slowCall(ctx context.Context, storage Storage, delta int64) error {
+	if s, ok := storage.(SlowCallStorage); ok {
+		return s.Slow(ctx, delta)
+	}
+
+	return storage.Failure(ctx, delta)
+}
@@ -0,0 +1,158 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/stretchr/testify/assert"
+	mockPkg "github.com/stretchr/testify/mock"
+)
+
+func TestFailoverStorage(t *testing.T) {
+	primary := &mock.Storage{}
+	logger := &mock.Logger{}
+	storageErr := errors.New("connection refused")
+
+	storage := circuitbreaker.NewFailoverStorage(
+		primary,
+		circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(2), circuitbreaker.WithOpenWindow(time.Minute)),
+		circuitbreaker.WithStorageErrorThreshold(2),
+		circuitbreaker.WithStorageRetryInterval(time.Minute),
+		circuitbreaker.WithFailoverLogger(logger),
+	)
+
+	t.Run("primary storage works, expect to use it", func(t *testing.T) {
+		primary.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+		primary.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+
+		assert.Nil(t, storage.Failure(context.Background(), 1))
+
+		state, err := storage.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+		assert.False(t, storage.Degraded())
+
+		primary.AssertExpectations(t)
+	})
+
+	t.Run("primary storage fails, expect to fall back to shadow and keep tracking outcomes", func(t *testing.T) {
+		primary.On("Failure", context.Background(), int64(1)).Return(storageErr).Once()
+		primary.On("Failure", context.Background(), int64(2)).Return(storageErr).Once()
+		logger.On("Error", mockPkg.MatchedBy(func(err error) bool { return errors.Is(err, storageErr) })).Once()
+
+		assert.Nil(t, storage.Failure(context.Background(), 1))
+		assert.True(t, storage.Degraded())
+		assert.Nil(t, storage.Failure(context.Background(), 1))
+
+		state, err := storage.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+
+		primary.AssertExpectations(t)
+	})
+
+	t.Run("primary storage reached error threshold, expect to not call it until retry interval", func(t *testing.T) {
+		state, err := storage.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+
+		primary.AssertExpectations(t)
+	})
+}
+
+func TestFailoverStorage_Reconcile(t *testing.T) {
+	primary := &mock.Storage{}
+	logger := &mock.Logger{}
+
+	storage := circuitbreaker.NewFailoverStorage(
+		primary,
+		circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(2), circuitbreaker.WithOpenWindow(time.Minute)),
+		circuitbreaker.WithFailoverLogger(logger),
+	)
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)
+
+	t.Run("primary storage fails, expect stat to be degraded", func(t *testing.T) {
+		storageErr := errors.New("connection refused")
+		primary.On("Failure", context.Background(), int64(1)).Return(storageErr).Once()
+		logger.On("Error", mockPkg.MatchedBy(func(err error) bool { return errors.Is(err, storageErr) })).Once()
+
+		breaker.Done(context.Background(), errors.New("some error"))
+		breaker.Done(context.Background(), errors.New("some error"))
+
+		// primary is still failing when pending outcomes are written on state check.
+		primary.On("Failure", context.Background(), int64(2)).Return(storageErr).Once()
+
+		assert.Equal(t, circuitbreaker.Stat{State: circuitbreaker.StateOpen, Failure: 2, Degraded: true}, breaker.Stat(context.Background()))
+
+		primary.AssertExpectations(t)
+	})
+
+	t.Run("primary storage is back, expect pending outcomes to be written and primary to be used", func(t *testing.T) {
+		primary.On("Failure", context.Background(), int64(2)).Return(nil).Once()
+		primary.On("GetState", context.Background()).Return(circuitbreaker.StateOpen, nil).Once()
+		logger.On("Info", "primary storage is back, pending results are written to it").Once()
+
+		assert.Equal(t, circuitbreaker.Stat{State: circuitbreaker.StateOpen, Failure: 2}, breaker.Stat(context.Background()))
+
+		primary.AssertExpectations(t)
+		logger.AssertExpectations(t)
+	})
+}
+
+func TestFailoverStorage_PrimaryStillDown(t *testing.T) {
+	primary := &mock.Storage{}
+	logger := &mock.Logger{}
+	storageErr := errors.New("connection refused")
+	shadow := circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(1), circuitbreaker.WithOpenWindow(time.Minute))
+
+	storage := circuitbreaker.NewFailoverStorage(
+		primary,
+		shadow,
+		circuitbreaker.WithStorageErrorThreshold(100),
+		circuitbreaker.WithFailoverLogger(logger),
+	)
+
+	t.Run("caller gave up, expect storage to not be degraded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		primary.On("Failure", ctx, int64(1)).Return(context.Canceled).Once()
+		primary.On("GetState", ctx).Return(circuitbreaker.StateClose, context.Canceled).Once()
+		primary.On("Reset", ctx).Return(context.Canceled).Once()
+
+		assert.Equal(t, context.Canceled, storage.Failure(ctx, 1))
+
+		_, err := storage.GetState(ctx)
+		assert.Equal(t, context.Canceled, err)
+
+		assert.Equal(t, context.Canceled, storage.Reset(ctx))
+		assert.False(t, storage.Degraded())
+
+		primary.AssertExpectations(t)
+	})
+
+	t.Run("nothing is pending and primary is still down, expect shadow to be kept", func(t *testing.T) {
+		primary.On("GetState", context.Background()).Return(circuitbreaker.StateClose, storageErr).Times(4)
+		logger.On("Error", mockPkg.MatchedBy(func(err error) bool { return errors.Is(err, storageErr) })).Once()
+
+		assert.Nil(t, shadow.Failure(context.Background(), 1))
+
+		for i := 0; i < 4; i++ {
+			state, err := storage.GetState(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, circuitbreaker.StateOpen, state)
+			assert.True(t, storage.Degraded())
+		}
+
+		primary.AssertExpectations(t)
+		logger.AssertExpectations(t)
+	})
+}
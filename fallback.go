@@ -0,0 +1,49 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// FallbackFn is type of callable that DoWithFallback call when fn is rejected or failed.
+// err is the reason, ErrIsOpen if circuit is open, ErrBulkheadFull if max concurrent calls are running,
+// ErrTimeout if fn timed out, otherwise the error of fn.
+type FallbackFn func(ctx context.Context, err error) (interface{}, error)
+
+// DoWithFallback is like Do, but if circuit is open or fn is failed, it returns result of the fallback.
+// fallback calls are reported apart in Stat.
+func (s *Circuit) DoWithFallback(ctx context.Context, fn Fn, fallback FallbackFn) (interface{}, error) {
+	return s.DoContextWithFallback(ctx, func(context.Context) (interface{}, error) { return fn() }, fallback)
+}
+
+// DoContextWithFallback is like DoWithFallback, but fn get a ctx that is canceled when the call times out, like DoContext.
+func (s *Circuit) DoContextWithFallback(ctx context.Context, fn ContextFn, fallback FallbackFn) (interface{}, error) {
+	res, err := s.DoContext(ctx, fn)
+	if err == nil || fallback == nil {
+		return res, err
+	}
+
+	atomic.AddInt64(&s.fallback, 1)
+
+	return fallback(ctx, err)
+}
+
+// FallbackChain call fallbacks in order until one of them succeed, each fallback get the error of previous one.
+func FallbackChain(fallbacks ...FallbackFn) FallbackFn {
+	return func(ctx context.Context, err error) (res interface{}, _ error) {
+		for _, fallback := range fallbacks {
+			if res, err = fallback(ctx, err); err == nil {
+				return res, nil
+			}
+		}
+
+		return res, err
+	}
+}
+
+// GuardFallback protect the fallback with its own circuit, so a failing fallback is not called again and again.
+func GuardFallback(manager Manager, fallback FallbackFn) FallbackFn {
+	return func(ctx context.Context, err error) (interface{}, error) {
+		return manager.Do(ctx, func() (interface{}, error) { return fallback(ctx, err) })
+	}
+}
@@ -0,0 +1,119 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_DoWithFallback(t *testing.T) {
+	storage := &mock.Storage{}
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)
+
+	var reasons []error
+
+	fallback := func(ctx context.Context, err error) (interface{}, error) {
+		reasons = append(reasons, err)
+
+		return "fallback", nil
+	}
+
+	t.Run("circuit is close/available, expect to get result of fn", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Twice()
+
+		response, err := breaker.DoWithFallback(context.Background(), func() (interface{}, error) { return "response", nil }, fallback)
+		assert.Nil(t, err)
+		assert.Equal(t, "response", response)
+		assert.Empty(t, reasons)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("service call is failed, expect to get result of fallback", func(t *testing.T) {
+		expectedErr := errors.New("service faild")
+
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+
+		response, err := breaker.DoWithFallback(context.Background(), func() (interface{}, error) { return nil, expectedErr }, fallback)
+		assert.Nil(t, err)
+		assert.Equal(t, "fallback", response)
+		assert.Equal(t, []error{expectedErr}, reasons)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("circuit is not available, expect fallback to get ErrIsOpen", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateOpen, nil).Twice()
+
+		response, err := breaker.DoWithFallback(context.Background(), nil, fallback)
+		assert.Nil(t, err)
+		assert.Equal(t, "fallback", response)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, reasons[1])
+
+		stat := breaker.Stat(context.Background())
+		assert.Equal(t, int64(2), stat.Fallback)
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestFallbackChain(t *testing.T) {
+	firstErr := errors.New("first fallback failed")
+	secondErr := errors.New("second fallback failed")
+
+	failing := func(expected, err error) circuitbreaker.FallbackFn {
+		return func(ctx context.Context, reason error) (interface{}, error) {
+			assert.Equal(t, expected, reason)
+
+			return nil, err
+		}
+	}
+
+	t.Run("expect to get result of first succeeded fallback", func(t *testing.T) {
+		chain := circuitbreaker.FallbackChain(
+			failing(circuitbreaker.ErrIsOpen, firstErr),
+			func(ctx context.Context, err error) (interface{}, error) { return "second", nil },
+			failing(nil, secondErr),
+		)
+
+		response, err := chain(context.Background(), circuitbreaker.ErrIsOpen)
+		assert.Nil(t, err)
+		assert.Equal(t, "second", response)
+	})
+
+	t.Run("all fallbacks failed, expect to get the last error", func(t *testing.T) {
+		chain := circuitbreaker.FallbackChain(failing(circuitbreaker.ErrIsOpen, firstErr), failing(firstErr, secondErr))
+
+		_, err := chain(context.Background(), circuitbreaker.ErrIsOpen)
+		assert.Equal(t, secondErr, err)
+	})
+}
+
+func TestGuardFallback(t *testing.T) {
+	storage := &mock.Storage{}
+	guard := circuitbreaker.NewCircuit(circuitbreaker.WithStorage(storage))
+
+	t.Run("circuit of fallback is not available, expect to get ErrIsOpen", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateOpen, nil).Once()
+
+		fallback := circuitbreaker.GuardFallback(guard, func(ctx context.Context, err error) (interface{}, error) {
+			t.Fatal("fallback should not be called")
+
+			return nil, nil
+		})
+
+		_, err := fallback(context.Background(), errors.New("service faild"))
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+
+		storage.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,35 @@
+// Package grpc protects gRPC calls with circuits.
+package grpc
+
+import (
+	"github.com/mrsoftware/circuitbreaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultFailureCodes are status codes that are caused by an unhealthy service.
+// nolint:gochecknoglobals
+var DefaultFailureCodes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted}
+
+// CodeClassifier consider errors with one of these status codes as failure, and the other status codes as success.
+// if no code is passed, DefaultFailureCodes are used. errors that are not a gRPC status are failure.
+func CodeClassifier(failureCodes ...codes.Code) circuitbreaker.ErrorClassifier {
+	if len(failureCodes) == 0 {
+		failureCodes = DefaultFailureCodes
+	}
+
+	return func(err error) circuitbreaker.Outcome {
+		st, ok := status.FromError(err)
+		if !ok {
+			return circuitbreaker.OutcomeFailure
+		}
+
+		for _, code := range failureCodes {
+			if st.Code() == code {
+				return circuitbreaker.OutcomeFailure
+			}
+		}
+
+		return circuitbreaker.OutcomeSuccess
+	}
+}
@@ -0,0 +1,34 @@
+package grpc_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/grpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodeClassifier(t *testing.T) {
+	testCases := []struct {
+		Error    error
+		Codes    []codes.Code
+		Expected circuitbreaker.Outcome
+	}{
+		{Error: status.Error(codes.Unavailable, "unavailable"), Expected: circuitbreaker.OutcomeFailure},
+		{Error: status.Error(codes.NotFound, "not found"), Expected: circuitbreaker.OutcomeSuccess},
+		{Error: status.Error(codes.NotFound, "not found"), Codes: []codes.Code{codes.NotFound}, Expected: circuitbreaker.OutcomeFailure},
+		{Error: status.Error(codes.Unavailable, "unavailable"), Codes: []codes.Code{codes.NotFound}, Expected: circuitbreaker.OutcomeSuccess},
+		{Error: errors.New("not a status"), Expected: circuitbreaker.OutcomeFailure},
+	}
+
+	for index, item := range testCases {
+		item := item
+		t.Run(fmt.Sprintf("running test %d", index), func(t *testing.T) {
+			assert.Equal(t, item.Expected, grpc.CodeClassifier(item.Codes...)(item.Error))
+		})
+	}
+}
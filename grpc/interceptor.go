@@ -0,0 +1,206 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/mrsoftware/circuitbreaker"
+	stdgrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrIsOpen is returned by interceptors when circuit is open.
+// nolint:gochecknoglobals
+var ErrIsOpen = status.Error(codes.Unavailable, circuitbreaker.ErrIsOpen.Error())
+
+// guard is a circuit and the options of an interceptor call.
+type guard struct {
+	ops     Options
+	manager circuitbreaker.Manager
+}
+
+// failure reports if err should be stored as failure.
+func (g guard) failure(err error) bool {
+	return CodeClassifier(g.ops.FailureCodes...)(err) == circuitbreaker.OutcomeFailure
+}
+
+// do call fn through the circuit, errors that are not failure are returned but stored as success.
+func (g guard) do(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	type result struct {
+		res interface{}
+		err error
+	}
+
+	r, err := circuitbreaker.Execute(ctx, g.manager, func(callCtx context.Context) (result, error) {
+		res, err := fn(callCtx)
+		// if the caller gave up, DoContext does not store the error.
+		if err != nil && (g.failure(err) || ctx.Err() != nil) {
+			return result{}, err
+		}
+
+		return result{res: res, err: err}, nil
+	})
+
+	switch {
+	case errors.Is(err, circuitbreaker.ErrIsOpen):
+		return nil, ErrIsOpen
+	case errors.Is(err, circuitbreaker.ErrTimeout):
+		return nil, status.Error(codes.DeadlineExceeded, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	return r.res, r.err
+}
+
+// done report result of a stream to circuit.
+func (g guard) done(ctx context.Context, err error) {
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+
+	switch {
+	case err == nil:
+		g.manager.Done(ctx, nil)
+	case ctx.Err() != nil:
+		// caller gave up, so the error does not say anything about the service.
+	case g.failure(err):
+		g.manager.Done(ctx, err)
+	default:
+		g.manager.Done(ctx, nil)
+	}
+}
+
+func newGuard(managers ManagerFunc, ops Options, target, method string) (guard, bool) {
+	manager := managers(ops.KeyFunc(target, method))
+
+	return guard{ops: ops, manager: manager}, manager != nil
+}
+
+// UnaryClientInterceptor guards unary calls with circuits, managers return the circuit of each call key.
+// when circuit is open, calls fail with ErrIsOpen that has codes.Unavailable.
+func UnaryClientInterceptor(managers ManagerFunc, options ...Option) stdgrpc.UnaryClientInterceptor {
+	ops := newOptions(TargetKey, options)
+
+	return func(
+		ctx context.Context, method string, req, reply interface{}, cc *stdgrpc.ClientConn, invoker stdgrpc.UnaryInvoker, opts ...stdgrpc.CallOption,
+	) error {
+		g, ok := newGuard(managers, ops, cc.Target(), method)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		_, err := g.do(ctx, func(ctx context.Context) (interface{}, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		})
+
+		return err
+	}
+}
+
+// StreamClientInterceptor guards streaming calls with circuits, managers return the circuit of each call key.
+// result of the stream is reported to circuit when it ends, and when circuit is open, streams fail with ErrIsOpen.
+func StreamClientInterceptor(managers ManagerFunc, options ...Option) stdgrpc.StreamClientInterceptor {
+	ops := newOptions(TargetKey, options)
+
+	return func(
+		ctx context.Context, desc *stdgrpc.StreamDesc, cc *stdgrpc.ClientConn, method string, streamer stdgrpc.Streamer, opts ...stdgrpc.CallOption,
+	) (stdgrpc.ClientStream, error) {
+		g, ok := newGuard(managers, ops, cc.Target(), method)
+		if !ok {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		if !g.manager.IsAvailable(ctx) {
+			return nil, ErrIsOpen
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			g.done(ctx, err)
+
+			return nil, err
+		}
+
+		return &clientStream{ClientStream: stream, ctx: ctx, guard: g, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// clientStream report result of stream to circuit when it ends.
+type clientStream struct {
+	stdgrpc.ClientStream
+	ctx   context.Context
+	guard guard
+	once  sync.Once
+	// serverStreams is false if server sends one response, so the stream ends when it is received.
+	serverStreams bool
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.once.Do(func() { s.guard.done(s.ctx, err) })
+	}
+
+	return err
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF means the stream is ended, and its status is returned by RecvMsg.
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.once.Do(func() { s.guard.done(s.ctx, err) })
+	}
+
+	return err
+}
+
+// UnaryServerInterceptor guards unary handlers with circuits, managers return the circuit of each call key.
+// when circuit is open, the handler is not called and ErrIsOpen is returned.
+func UnaryServerInterceptor(managers ManagerFunc, options ...Option) stdgrpc.UnaryServerInterceptor {
+	ops := newOptions(MethodKey, options)
+
+	return func(ctx context.Context, req interface{}, info *stdgrpc.UnaryServerInfo, handler stdgrpc.UnaryHandler) (interface{}, error) {
+		g, ok := newGuard(managers, ops, "", info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		// handler is called once even if circuit has retries, they are for the clients.
+		if !g.manager.IsAvailable(ctx) {
+			return nil, ErrIsOpen
+		}
+
+		res, err := handler(ctx, req)
+		g.done(ctx, err)
+
+		return res, err
+	}
+}
+
+// StreamServerInterceptor guards streaming handlers with circuits, managers return the circuit of each call key.
+// when circuit is open, the handler is not called and ErrIsOpen is returned.
+func StreamServerInterceptor(managers ManagerFunc, options ...Option) stdgrpc.StreamServerInterceptor {
+	ops := newOptions(MethodKey, options)
+
+	return func(srv interface{}, ss stdgrpc.ServerStream, info *stdgrpc.StreamServerInfo, handler stdgrpc.StreamHandler) error {
+		g, ok := newGuard(managers, ops, "", info.FullMethod)
+		if !ok {
+			return handler(srv, ss)
+		}
+
+		ctx := ss.Context()
+
+		if !g.manager.IsAvailable(ctx) {
+			return ErrIsOpen
+		}
+
+		err := handler(srv, ss)
+		g.done(ctx, err)
+
+		return err
+	}
+}
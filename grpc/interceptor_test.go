@@ -0,0 +1,244 @@
+package grpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/grpc"
+	"github.com/stretchr/testify/assert"
+	stdgrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer responds to Check with err, and to Watch with one response and then err.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	err    error
+	checks atomic.Int64
+}
+
+func (h *healthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	h.checks.Add(1)
+
+	if h.err != nil {
+		return nil, h.err
+	}
+
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (h *healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+		return err
+	}
+
+	return h.err
+}
+
+func newCircuit(options ...circuitbreaker.Option) *circuitbreaker.Circuit {
+	return circuitbreaker.NewCircuit(append([]circuitbreaker.Option{
+		circuitbreaker.WithStorage(circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(2),
+			circuitbreaker.WithOpenWindow(time.Minute),
+			circuitbreaker.WithHalfOpenWindow(5*time.Second),
+		)),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	}, options...)...)
+}
+
+func dial(t *testing.T, health *healthServer, serverOptions []stdgrpc.ServerOption, dialOptions ...stdgrpc.DialOption) grpc_health_v1.HealthClient {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := stdgrpc.NewServer(serverOptions...)
+	grpc_health_v1.RegisterHealthServer(server, health)
+
+	go func() { _ = server.Serve(listener) }()
+
+	t.Cleanup(server.Stop)
+
+	dialOptions = append(dialOptions,
+		stdgrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		stdgrpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	conn, err := stdgrpc.Dial("bufnet", dialOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func watch(client grpc_health_v1.HealthClient) error {
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	health := &healthServer{}
+	circuit := newCircuit()
+	keys := make(map[string]bool)
+
+	client := dial(t, health, nil, stdgrpc.WithUnaryInterceptor(grpc.UnaryClientInterceptor(func(key string) circuitbreaker.Manager {
+		keys[key] = true
+
+		return circuit
+	})))
+
+	t.Run("call succeeds, expect success to be reported", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]bool{"bufnet": true}, keys)
+		assert.Equal(t, int64(1), circuit.Stat(context.Background()).Success)
+	})
+
+	t.Run("call fails with a status that is not failure, expect error and success to be reported", func(t *testing.T) {
+		health.err = status.Error(codes.NotFound, "not found")
+
+		_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, circuitbreaker.Stat{State: circuitbreaker.StateClose, Success: 2}, circuit.Stat(context.Background()))
+	})
+
+	t.Run("call fails with failure status, expect circuit to open and fail fast", func(t *testing.T) {
+		health.err = status.Error(codes.Unavailable, "unavailable")
+
+		for i := 0; i < 2; i++ {
+			_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+		}
+
+		health.err = nil
+
+		_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		assert.Equal(t, grpc.ErrIsOpen, err)
+		assert.Equal(t, int64(2), circuit.Stat(context.Background()).Failure)
+	})
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	health := &healthServer{err: status.Error(codes.ResourceExhausted, "exhausted")}
+	circuit := newCircuit()
+
+	client := dial(t, health, nil, stdgrpc.WithStreamInterceptor(grpc.StreamClientInterceptor(
+		func(key string) circuitbreaker.Manager { return circuit },
+		grpc.WithKeyFunc(grpc.TargetMethodKey),
+	)))
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(watch(client)))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(watch(client)))
+	assert.Equal(t, grpc.ErrIsOpen, watch(client))
+	assert.Equal(t, int64(2), circuit.Stat(context.Background()).Failure)
+}
+
+func TestStreamClientInterceptor_Success(t *testing.T) {
+	t.Run("server stream ends, expect success to be reported", func(t *testing.T) {
+		circuit := newCircuit()
+
+		client := dial(t, &healthServer{}, nil, stdgrpc.WithStreamInterceptor(grpc.StreamClientInterceptor(
+			func(key string) circuitbreaker.Manager { return circuit },
+		)))
+
+		assert.Equal(t, io.EOF, watch(client))
+		assert.Equal(t, int64(1), circuit.Stat(context.Background()).Success)
+	})
+
+	t.Run("client stream received the response, expect success to be reported", func(t *testing.T) {
+		circuit := newCircuit()
+		interceptor := grpc.StreamClientInterceptor(func(key string) circuitbreaker.Manager { return circuit })
+
+		conn, err := stdgrpc.Dial("bufnet", stdgrpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer conn.Close()
+
+		streamer := func(context.Context, *stdgrpc.StreamDesc, *stdgrpc.ClientConn, string, ...stdgrpc.CallOption) (stdgrpc.ClientStream, error) {
+			return responseStream{}, nil
+		}
+
+		stream, err := interceptor(context.Background(), &stdgrpc.StreamDesc{ClientStreams: true}, conn, "/service/Upload", streamer)
+		assert.Nil(t, err)
+
+		// like CloseAndRecv of a client streaming call.
+		assert.Nil(t, stream.CloseSend())
+		assert.Nil(t, stream.RecvMsg(nil))
+		assert.Equal(t, int64(1), circuit.Stat(context.Background()).Success)
+	})
+}
+
+// responseStream is a client stream that receives one response.
+type responseStream struct {
+	stdgrpc.ClientStream
+}
+
+func (responseStream) CloseSend() error { return nil }
+
+func (responseStream) RecvMsg(interface{}) error { return nil }
+
+func TestServerInterceptors(t *testing.T) {
+	health := &healthServer{err: status.Error(codes.Internal, "internal")}
+	circuits := map[string]*circuitbreaker.Circuit{
+		"/grpc.health.v1.Health/Check": newCircuit(),
+		"/grpc.health.v1.Health/Watch": newCircuit(),
+	}
+	managers := func(key string) circuitbreaker.Manager { return circuits[key] }
+
+	client := dial(t, health, []stdgrpc.ServerOption{
+		stdgrpc.UnaryInterceptor(grpc.UnaryServerInterceptor(managers, grpc.WithFailureCodes(codes.Internal))),
+		stdgrpc.StreamInterceptor(grpc.StreamServerInterceptor(managers, grpc.WithFailureCodes(codes.Internal))),
+	})
+
+	t.Run("unary handler fails, expect circuit to open", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			assert.Equal(t, codes.Internal, status.Code(err))
+		}
+
+		_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, circuitbreaker.StateOpen, circuits["/grpc.health.v1.Health/Check"].GetState(context.Background()))
+	})
+
+	t.Run("stream handler fails, expect circuit to open", func(t *testing.T) {
+		assert.Equal(t, codes.Internal, status.Code(watch(client)))
+		assert.Equal(t, codes.Internal, status.Code(watch(client)))
+		assert.Equal(t, codes.Unavailable, status.Code(watch(client)))
+		assert.Equal(t, circuitbreaker.StateOpen, circuits["/grpc.health.v1.Health/Watch"].GetState(context.Background()))
+	})
+}
+
+func TestUnaryServerInterceptor_Retry(t *testing.T) {
+	health := &healthServer{err: status.Error(codes.Internal, "internal")}
+	circuit := newCircuit(circuitbreaker.WithRetry(3, time.Millisecond, 0))
+	managers := func(key string) circuitbreaker.Manager { return circuit }
+
+	client := dial(t, health, []stdgrpc.ServerOption{
+		stdgrpc.UnaryInterceptor(grpc.UnaryServerInterceptor(managers, grpc.WithFailureCodes(codes.Internal))),
+	})
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, int64(1), health.checks.Load(), "handler is called once even if circuit has retries")
+	assert.Equal(t, int64(1), circuit.Stat(context.Background()).Failure)
+}
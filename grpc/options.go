@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"github.com/mrsoftware/circuitbreaker"
+	"google.golang.org/grpc/codes"
+)
+
+// KeyFunc select the key of circuit that guards a call, target is empty for server calls.
+type KeyFunc func(target, fullMethod string) string
+
+// TargetKey use target of client connection as circuit key, so each target has its own circuit.
+func TargetKey(target, fullMethod string) string {
+	return target
+}
+
+// MethodKey use the full method name as circuit key, so each method has its own circuit.
+func MethodKey(target, fullMethod string) string {
+	return fullMethod
+}
+
+// TargetMethodKey use both target and full method name as circuit key.
+func TargetMethodKey(target, fullMethod string) string {
+	return target + fullMethod
+}
+
+// ManagerFunc return the circuit of key, it must return the same Manager for the same key.
+// if it returns nil, the call is not guarded.
+type ManagerFunc func(key string) circuitbreaker.Manager
+
+// Options is interceptors options.
+type Options struct {
+	// KeyFunc select circuit key of calls.
+	KeyFunc KeyFunc
+	// FailureCodes are status codes that are reported to circuit as failure.
+	FailureCodes []codes.Code
+}
+
+// Option configures interceptors.
+type Option func(*Options)
+
+// WithKeyFunc sets the function that select circuit key of calls,
+// default is TargetKey for client interceptors and MethodKey for server interceptors.
+func WithKeyFunc(key KeyFunc) Option {
+	return func(o *Options) {
+		o.KeyFunc = key
+	}
+}
+
+// WithFailureCodes sets status codes that are reported to circuit as failure, default is DefaultFailureCodes.
+func WithFailureCodes(failureCodes ...codes.Code) Option {
+	return func(o *Options) {
+		o.FailureCodes = failureCodes
+	}
+}
+
+func newOptions(key KeyFunc, options []Option) Options {
+	ops := Options{KeyFunc: key, FailureCodes: DefaultFailureCodes}
+
+	for _, op := range options {
+		op(&ops)
+	}
+
+	return ops
+}
@@ -0,0 +1,78 @@
+// Package http guards net/http clients and handlers with circuits.
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mrsoftware/circuitbreaker"
+)
+
+// KeyFunc select the key of circuit that guards the request.
+type KeyFunc func(r *http.Request) string
+
+// HostKey use the request host as circuit key, so each host has its own circuit.
+func HostKey(r *http.Request) string {
+	if r.URL != nil && r.URL.Host != "" {
+		return r.URL.Host
+	}
+
+	return r.Host
+}
+
+// PathKey use the request path as circuit key, so each route has its own circuit.
+func PathKey(r *http.Request) string {
+	return r.URL.Path
+}
+
+// MethodKey use the request method and path as circuit key.
+func MethodKey(r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
+
+// HeaderKey use value of the header as circuit key, like a tenant header.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// ManagerFunc return the circuit of key, it must return the same Manager for the same key.
+// if it returns nil, the request is not guarded.
+type ManagerFunc func(key string) circuitbreaker.Manager
+
+// StatusFunc reports if a response status code is a failure of the service.
+type StatusFunc func(code int) bool
+
+// DefaultFailureStatus consider 5xx and 429 status codes as failure.
+func DefaultFailureStatus(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
+
+// OpenError is returned when circuit of the request is open, it wraps circuitbreaker.ErrIsOpen.
+type OpenError struct {
+	Key string
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("CircuitBreaker: circuit of %q is open", e.Key)
+}
+
+func (e *OpenError) Unwrap() error {
+	return circuitbreaker.ErrIsOpen
+}
+
+// StatusError is the failure that is reported to circuit for a response with failure status.
+// it implements circuitbreaker.StatusCoder, so it works with circuitbreaker.HTTPStatusClassifier.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("CircuitBreaker: external service responded with status %d", e.Code)
+}
+
+// StatusCode of the response.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// openRemaining is a Manager that knows when its open circuit accepts calls again, like circuitbreaker.Circuit.
+type openRemaining interface {
+	OpenRemaining(ctx context.Context) time.Duration
+}
+
+// UnavailableHandler responds with 503 status.
+func UnavailableHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	})
+}
+
+// NewMiddleware create a middleware that guards handlers with circuits, managers return the circuit of each request key.
+// when circuit is open, the handler is not called and OpenHandler responds with Retry-After header of the remaining open window.
+// status of handler response is reported to circuit, and a panic of handler is reported as failure.
+func NewMiddleware(managers ManagerFunc, options ...Option) func(http.Handler) http.Handler {
+	ops := Options{KeyFunc: PathKey, FailureStatus: DefaultFailureStatus, OpenHandler: UnavailableHandler()}
+
+	for _, op := range options {
+		op(&ops)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			manager := managers(ops.KeyFunc(r))
+			if manager == nil {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			ctx := r.Context()
+
+			if !manager.IsAvailable(ctx) {
+				if remaining, ok := manager.(openRemaining); ok {
+					setRetryAfter(w, remaining.OpenRemaining(ctx))
+				}
+
+				ops.OpenHandler.ServeHTTP(w, r)
+
+				return
+			}
+
+			writer := &statusWriter{ResponseWriter: w}
+
+			defer func() {
+				if p := recover(); p != nil {
+					manager.Done(ctx, &StatusError{Code: http.StatusInternalServerError})
+
+					panic(p)
+				}
+
+				if ops.FailureStatus(writer.status()) {
+					manager.Done(ctx, &StatusError{Code: writer.status()})
+
+					return
+				}
+
+				manager.Done(ctx, nil)
+			}()
+
+			next.ServeHTTP(writer, r)
+		})
+	}
+}
+
+// setRetryAfter sets Retry-After header in seconds, rounded up.
+func setRetryAfter(w http.ResponseWriter, remaining time.Duration) {
+	if remaining <= 0 {
+		return
+	}
+
+	seconds := int64((remaining + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// statusWriter records status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying writer does.
+func (w *statusWriter) Flush() {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer does, so upgrade handlers like WebSocket work.
+// a hijacked connection is recorded as switching protocols.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.code == 0 {
+		w.code = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+
+	return w.code
+}
@@ -0,0 +1,137 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	cbhttp "github.com/mrsoftware/circuitbreaker/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	status := http.StatusOK
+	circuit := newCircuit()
+
+	handler := cbhttp.NewMiddleware(func(key string) circuitbreaker.Manager { return circuit })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}),
+	)
+
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		return recorder
+	}
+
+	t.Run("handler responds ok, expect success to be reported", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve().Code)
+		assert.Equal(t, circuitbreaker.Stat{State: circuitbreaker.StateClose, Success: 1}, circuit.Stat(context.Background()))
+	})
+
+	t.Run("handler responds 5xx, expect failures to be reported and circuit to open", func(t *testing.T) {
+		status = http.StatusBadGateway
+
+		assert.Equal(t, http.StatusBadGateway, serve().Code)
+		assert.Equal(t, http.StatusBadGateway, serve().Code)
+		assert.Equal(t, circuitbreaker.StateOpen, circuit.GetState(context.Background()))
+	})
+
+	t.Run("circuit is open, expect 503 with retry after", func(t *testing.T) {
+		recorder := serve()
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, "55", recorder.Header().Get("Retry-After"))
+	})
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	circuits := map[string]*circuitbreaker.Circuit{"tenant-a": newCircuit()}
+
+	handler := cbhttp.NewMiddleware(
+		func(key string) circuitbreaker.Manager {
+			if circuit, ok := circuits[key]; ok {
+				return circuit
+			}
+
+			return nil
+		},
+		cbhttp.WithKeyFunc(cbhttp.HeaderKey("X-Tenant")),
+		cbhttp.WithFailureStatus(func(code int) bool { return code != http.StatusOK }),
+		cbhttp.WithOpenHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	serve := func(tenant string) int {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Tenant", tenant)
+		handler.ServeHTTP(recorder, request)
+
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve("tenant-a"))
+	assert.Equal(t, http.StatusNotFound, serve("tenant-a"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("tenant-a"))
+	assert.Equal(t, http.StatusNotFound, serve("tenant-b"))
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	circuit := newCircuit()
+
+	handler := cbhttp.NewMiddleware(func(key string) circuitbreaker.Manager { return circuit })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") }),
+	)
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Equal(t, int64(1), circuit.Stat(context.Background()).Failure)
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	circuit := newCircuit()
+
+	server := httptest.NewServer(cbhttp.NewMiddleware(func(key string) circuitbreaker.Manager { return circuit })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hijacker, ok := w.(http.Hijacker)
+			if !ok {
+				http.Error(w, "hijacking is not supported", http.StatusInternalServerError)
+
+				return
+			}
+
+			conn, rw, err := hijacker.Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			_ = rw.Flush()
+		}),
+	))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	assert.Nil(t, err)
+
+	body, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "hijacked", string(body))
+	res.Body.Close()
+
+	// result is reported after handler returns, that may be after the response is read.
+	assert.Eventually(t, func() bool {
+		return circuit.Stat(context.Background()).Success == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+)
+
+// Options is options of Transport and Middleware.
+type Options struct {
+	// Base is the RoundTripper that Transport sends requests with.
+	Base http.RoundTripper
+	// KeyFunc select circuit key of requests.
+	KeyFunc KeyFunc
+	// FailureStatus reports which response status codes are failure.
+	FailureStatus StatusFunc
+	// OpenHandler is what Middleware responds with when circuit is open.
+	OpenHandler http.Handler
+}
+
+// Option configures Transport and Middleware.
+type Option func(*Options)
+
+// WithBase sets the RoundTripper that sends the requests, default is http.DefaultTransport.
+func WithBase(base http.RoundTripper) Option {
+	return func(o *Options) {
+		o.Base = base
+	}
+}
+
+// WithKeyFunc sets the function that select circuit key of requests,
+// default is HostKey for Transport and PathKey for Middleware.
+func WithKeyFunc(key KeyFunc) Option {
+	return func(o *Options) {
+		o.KeyFunc = key
+	}
+}
+
+// WithFailureStatus sets the function that reports which response status codes are failure, default is DefaultFailureStatus.
+func WithFailureStatus(status StatusFunc) Option {
+	return func(o *Options) {
+		o.FailureStatus = status
+	}
+}
+
+// WithOpenHandler sets the handler that Middleware responds with when circuit is open,
+// Retry-After header is already set when it is known. default is UnavailableHandler.
+func WithOpenHandler(handler http.Handler) Option {
+	return func(o *Options) {
+		o.OpenHandler = handler
+	}
+}
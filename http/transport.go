@@ -0,0 +1,214 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mrsoftware/circuitbreaker"
+)
+
+var _ http.RoundTripper = &Transport{}
+
+// Transport is a http.RoundTripper that guards outgoing requests with circuits.
+// transport errors and responses with failure status are reported to circuit as failure,
+// but the response is still returned to caller as is.
+// if circuit is open, the request is not sent and an *OpenError is returned.
+type Transport struct {
+	ops      Options
+	managers ManagerFunc
+}
+
+// NewTransport create new instance of Transport, managers return the circuit of each request key.
+func NewTransport(managers ManagerFunc, options ...Option) *Transport {
+	ops := Options{Base: http.DefaultTransport, KeyFunc: HostKey, FailureStatus: DefaultFailureStatus}
+
+	for _, op := range options {
+		op(&ops)
+	}
+
+	return &Transport{ops: ops, managers: managers}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.ops.KeyFunc(req)
+
+	manager := t.managers(key)
+	if manager == nil {
+		return t.ops.Base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+
+	// a request that is not idempotent or has a body that can not be rewound is sent once, even if circuit has retries.
+	if !replayable(req) {
+		ctx = circuitbreaker.ContextWithoutRetry(ctx)
+	}
+
+	var attempts attempts
+
+	response, err := circuitbreaker.Execute(ctx, manager, func(ctx context.Context) (*http.Response, error) {
+		attemptReq, err := attempts.next(req)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := t.send(ctx, attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		// call timed out, nobody is waiting for the response.
+		if ctx.Err() != nil {
+			response.Body.Close()
+
+			return nil, ctx.Err()
+		}
+
+		if t.ops.FailureStatus(response.StatusCode) {
+			attempts.failed(response)
+
+			return response, &StatusError{Code: response.StatusCode}
+		}
+
+		return response, nil
+	})
+
+	var statusErr *StatusError
+
+	switch {
+	case err == nil:
+		return response, nil
+	case errors.As(err, &statusErr) && response != nil:
+		return response, nil
+	}
+
+	// the response of last failed attempt is not returned.
+	attempts.close()
+
+	if errors.Is(err, circuitbreaker.ErrIsOpen) {
+		return nil, &OpenError{Key: key}
+	}
+
+	return nil, err
+}
+
+// replayable reports if req can be sent again, like net/http it must be idempotent or have an idempotency key,
+// and its body must be rewindable.
+func replayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasXKey := req.Header["X-Idempotency-Key"]
+
+	return hasKey || hasXKey
+}
+
+// send the request with a ctx that is only canceled if the call times out.
+// the request does not use the ctx of call, because it is canceled after the call returns,
+// and that would cancel reading of the response body too.
+func (t *Transport) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	reqCtx, cancel := context.WithCancel(req.Context())
+	sent := make(chan struct{})
+
+	defer close(sent)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				cancel()
+			}
+		case <-sent:
+		}
+	}()
+
+	response, err := t.ops.Base.RoundTrip(req.WithContext(reqCtx))
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	// body of switching protocols response is also a writer, it is closed like the connection.
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
+	}
+
+	return response, nil
+}
+
+// cancelBody cancels the ctx of request when the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
+
+// attempts of a request when circuit retries it.
+type attempts struct {
+	lock  sync.Mutex
+	count int
+	// last is the response of last attempt that failed with a failure status, it is closed if it is not returned.
+	last *http.Response
+}
+
+// next returns the request of next attempt, its body is rewound if it is not the first one.
+func (a *attempts) next(req *http.Request) (*http.Request, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.count++
+
+	if a.last != nil {
+		a.last.Body.Close()
+		a.last = nil
+	}
+
+	if a.count == 1 || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrip must not modify the request of caller.
+	next := req.Clone(req.Context())
+	next.Body = body
+
+	return next, nil
+}
+
+func (a *attempts) failed(response *http.Response) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.last = response
+}
+
+func (a *attempts) close() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.last != nil {
+		a.last.Body.Close()
+		a.last = nil
+	}
+}
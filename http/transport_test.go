@@ -0,0 +1,215 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	cbhttp "github.com/mrsoftware/circuitbreaker/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCircuit() *circuitbreaker.Circuit {
+	return circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(2),
+			circuitbreaker.WithOpenWindow(time.Minute),
+			circuitbreaker.WithHalfOpenWindow(5*time.Second),
+		)),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+	)
+}
+
+func TestTransport(t *testing.T) {
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	circuit := newCircuit()
+	client := &http.Client{Transport: cbhttp.NewTransport(func(key string) circuitbreaker.Manager { return circuit })}
+
+	t.Run("service responds ok, expect to get the response", func(t *testing.T) {
+		res, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+
+		assert.Equal(t, circuitbreaker.Stat{State: circuitbreaker.StateClose, Success: 1}, circuit.Stat(context.Background()))
+	})
+
+	t.Run("service responds not found, expect to not count as failure", func(t *testing.T) {
+		status = http.StatusNotFound
+
+		res, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		res.Body.Close()
+
+		assert.Equal(t, int64(0), circuit.Stat(context.Background()).Failure)
+	})
+
+	t.Run("service responds 5xx and 429, expect to get the response and circuit to open", func(t *testing.T) {
+		for _, status = range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+			res, err := client.Get(server.URL)
+			assert.Nil(t, err)
+			assert.Equal(t, status, res.StatusCode)
+			res.Body.Close()
+		}
+
+		assert.Equal(t, circuitbreaker.StateOpen, circuit.GetState(context.Background()))
+	})
+
+	t.Run("circuit is open, expect to get open error", func(t *testing.T) {
+		_, err := client.Get(server.URL)
+
+		var openErr *cbhttp.OpenError
+		assert.True(t, errors.As(err, &openErr))
+		assert.True(t, errors.Is(err, circuitbreaker.ErrIsOpen))
+		assert.Equal(t, server.Listener.Addr().String(), openErr.Key)
+	})
+}
+
+func TestTransport_TransportError(t *testing.T) {
+	circuit := newCircuit()
+	transportErr := errors.New("connection refused")
+
+	client := &http.Client{Transport: cbhttp.NewTransport(
+		func(key string) circuitbreaker.Manager { return circuit },
+		cbhttp.WithBase(roundTripperFunc(func(*http.Request) (*http.Response, error) { return nil, transportErr })),
+	)}
+
+	_, err := client.Get("http://service.local")
+
+	var urlErr *url.Error
+	assert.True(t, errors.As(err, &urlErr))
+	assert.Equal(t, transportErr, urlErr.Err)
+	assert.Equal(t, int64(1), circuit.Stat(context.Background()).Failure)
+}
+
+func TestTransport_KeyFunc(t *testing.T) {
+	circuits := map[string]*circuitbreaker.Circuit{"a": newCircuit()}
+	calls := 0
+
+	client := &http.Client{Transport: cbhttp.NewTransport(
+		func(key string) circuitbreaker.Manager {
+			if circuit, ok := circuits[key]; ok {
+				return circuit
+			}
+
+			return nil
+		},
+		cbhttp.WithKeyFunc(func(r *http.Request) string { return r.Header.Get("X-Tenant") }),
+		cbhttp.WithFailureStatus(func(code int) bool { return code != http.StatusOK }),
+		cbhttp.WithBase(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: r}, nil
+		})),
+	)}
+
+	for _, tenant := range []string{"a", "b"} {
+		req, _ := http.NewRequest(http.MethodGet, "http://service.local", nil)
+		req.Header.Set("X-Tenant", tenant)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err)
+		res.Body.Close()
+	}
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, int64(1), circuits["a"].Stat(context.Background()).Failure)
+}
+
+func TestTransport_Retry(t *testing.T) {
+	var bodies []string
+	var responses []*closeBody
+
+	client := &http.Client{Transport: cbhttp.NewTransport(
+		func(key string) circuitbreaker.Manager {
+			return circuitbreaker.NewCircuit(
+				circuitbreaker.WithStorage(circuitbreaker.NewMemoryStorage(circuitbreaker.WithFailureRateThreshold(10))),
+				circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+				circuitbreaker.WithRetry(3, time.Millisecond, 0),
+			)
+		},
+		cbhttp.WithBase(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+
+			response := &closeBody{Reader: strings.NewReader("unavailable")}
+			responses = append(responses, response)
+
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: response, Request: r}, nil
+		})),
+	)}
+
+	send := func(method string, body io.Reader, header http.Header) {
+		bodies, responses = nil, nil
+
+		req, _ := http.NewRequest(method, "http://service.local", body)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		res, err := client.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		// the returned response is only closed by caller.
+		assert.False(t, responses[len(responses)-1].closed)
+		res.Body.Close()
+	}
+
+	t.Run("request is idempotent, expect each attempt to send the body and discarded responses to be closed", func(t *testing.T) {
+		send(http.MethodPut, strings.NewReader("payload"), nil)
+
+		assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+		assert.True(t, responses[0].closed)
+		assert.True(t, responses[1].closed)
+	})
+
+	t.Run("request is not idempotent, expect request to be sent once", func(t *testing.T) {
+		send(http.MethodPost, bytes.NewBufferString("payload"), nil)
+
+		assert.Equal(t, []string{"payload"}, bodies)
+	})
+
+	t.Run("request has idempotency key, expect request to be retried", func(t *testing.T) {
+		send(http.MethodPost, bytes.NewBufferString("payload"), http.Header{"Idempotency-Key": {"key"}})
+
+		assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+	})
+
+	t.Run("request body can not be rewound, expect request to be sent once", func(t *testing.T) {
+		send(http.MethodPut, io.NopCloser(strings.NewReader("payload")), nil)
+
+		assert.Equal(t, []string{"payload"}, bodies)
+	})
+}
+
+type closeBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeBody) Close() error {
+	b.closed = true
+
+	return nil
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -2,11 +2,20 @@ package circuitbreaker
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var _ Storage = &MemoryStorage{}
+var (
+	_ Storage           = &MemoryStorage{}
+	_ WindowedStorage   = &MemoryStorage{}
+	_ HalfOpenLimiter   = &MemoryStorage{}
+	_ SlowCallStorage   = &MemoryStorage{}
+	_ OpenWindowStorage = &MemoryStorage{}
+	_ OverrideStorage   = &MemoryStorage{}
+	_ OptionsStorage    = &MemoryStorage{}
+)
 
 // NewMemoryStorage create new instance of Memory.
 func NewMemoryStorage(options ...StorageOption) *MemoryStorage {
@@ -17,30 +26,109 @@ func NewMemoryStorage(options ...StorageOption) *MemoryStorage {
 	}
 
 	storage.lastErrorAt.Store(time.Time{})
+	storage.openWindow.Store(int64(storage.options.OpenWindow))
+	storage.window = newWindow(storage.options)
 
 	return &storage
 }
 
 // MemoryStorage is memory based storage for circuit breaker and is concurrent safe.
-// do not use single MemoryStorage for multiple service, it will override the other services state.
+// do not use single MemoryStorage for multiple service, it will override the other services state, use a Registry instead.
 type MemoryStorage struct {
 	options     StorageOptions
 	failures    atomic.Int64
+	slow        atomic.Int64
 	success     atomic.Int64
 	lastErrorAt atomic.Value
+	trials      atomic.Int64
+	// trips is number of consecutive failures in half open state, and openWindow is the current open window.
+	trips      atomic.Int64
+	openWindow atomic.Int64
+	// opened is set when threshold is reached, a failure below it only moves lastErrorAt.
+	opened     atomic.Bool
+	windowLock sync.Mutex
+	window     window
+	// override is the manual override, it expires at overrideUntil if it is not zero.
+	overrideLock  sync.RWMutex
+	override      Override
+	overrideUntil time.Time
+}
+
+// StorageOptions of storage.
+func (m *MemoryStorage) StorageOptions() StorageOptions {
+	return m.options
+}
+
+// Windowed reports if storage use a sliding window.
+func (m *MemoryStorage) Windowed() bool {
+	return m.window != nil
 }
 
 // Failure is responsible to store failures.
 func (m *MemoryStorage) Failure(ctx context.Context, delta int64) error {
-	m.lastErrorAt.Store(time.Now().UTC())
+	if m.disabled() {
+		return nil
+	}
+
+	if m.Windowed() && !m.tripped() && !m.record(delta, 0, 0) {
+		return nil
+	}
+
 	m.failures.Add(delta)
-	m.success.Store(0)
+	m.trip()
 
 	return nil
 }
 
+// Slow is responsible to store slow calls, they are stored as failures if SlowCallRateThreshold is 0.
+func (m *MemoryStorage) Slow(ctx context.Context, delta int64) error {
+	if m.options.SlowCallRateThreshold <= 0 {
+		return m.Failure(ctx, delta)
+	}
+
+	if m.disabled() {
+		return nil
+	}
+
+	if m.Windowed() && !m.tripped() && !m.record(0, delta, 0) {
+		return nil
+	}
+
+	m.slow.Add(delta)
+	m.trip()
+
+	return nil
+}
+
+// trip start a new open window, it is longer for consecutive failures in half open state if backoff is set.
+// a failure in half open tail of a window that did not reach the threshold is not a consecutive one.
+func (m *MemoryStorage) trip() {
+	if m.halfOpen() && m.opened.Load() {
+		m.trips.Add(1)
+	}
+
+	if m.reachedThreshold() {
+		m.opened.Store(true)
+	}
+
+	m.openWindow.Store(int64(openWindow(m.trips.Load(), m.options)))
+	m.lastErrorAt.Store(time.Now().UTC())
+	m.success.Store(0)
+	m.trials.Store(0)
+}
+
 // Success is responsible to store success.
 func (m *MemoryStorage) Success(ctx context.Context, delta int64) error {
+	if m.disabled() {
+		return nil
+	}
+
+	if m.Windowed() && !m.tripped() {
+		m.record(0, 0, delta)
+
+		return nil
+	}
+
 	if m.success.Add(delta) >= m.options.SuccessRateThreshold {
 		return m.Reset(ctx)
 	}
@@ -48,10 +136,66 @@ func (m *MemoryStorage) Success(ctx context.Context, delta int64) error {
 	return nil
 }
 
+// AcquireHalfOpen reports if one more trial call is permitted in current half open window.
+func (m *MemoryStorage) AcquireHalfOpen(ctx context.Context) (bool, error) {
+	// trials are only limited after circuit is really tripped.
+	if m.options.PermittedCallsInHalfOpen <= 0 || !m.opened.Load() {
+		return true, nil
+	}
+
+	return m.trials.Add(1) <= m.options.PermittedCallsInHalfOpen, nil
+}
+
+// record outcomes in sliding window and report if failure or slow call rate reached the threshold.
+func (m *MemoryStorage) record(failures, slow, success int64) bool {
+	m.windowLock.Lock()
+	defer m.windowLock.Unlock()
+
+	m.window.record(failures, slow, success)
+
+	fCount, sCount, total := m.window.counts()
+
+	return exceedRate(fCount, sCount, total, m.options)
+}
+
+// tripped reports if circuit is in open or half open window.
+func (m *MemoryStorage) tripped() bool {
+	lastErrorAt := m.lastErrorAt.Load().(time.Time)
+
+	return !lastErrorAt.IsZero() && time.Now().UTC().Before(lastErrorAt.Add(m.currentOpenWindow()))
+}
+
+// halfOpen reports if circuit is in half open window.
+func (m *MemoryStorage) halfOpen() bool {
+	lastErrorAt := m.lastErrorAt.Load().(time.Time)
+	if lastErrorAt.IsZero() {
+		return false
+	}
+
+	errorExpireTTL := lastErrorAt.Add(m.currentOpenWindow()).Sub(time.Now().UTC())
+
+	return errorExpireTTL > 0 && errorExpireTTL <= m.options.HalfOpenWindow
+}
+
+func (m *MemoryStorage) currentOpenWindow() time.Duration {
+	return time.Duration(m.openWindow.Load())
+}
+
 // GetState current state.
 func (m *MemoryStorage) GetState(ctx context.Context) (State, error) {
+	switch m.currentOverride() {
+	case OverrideForceOpen:
+		return StateOpen, nil
+	case OverrideForceClose, OverrideDisable:
+		return StateClose, nil
+	}
+
 	lastErrorAt := m.lastErrorAt.Load().(time.Time)
-	errorExpireTTL := lastErrorAt.Add(m.options.OpenWindow).Sub(time.Now().UTC())
+	if lastErrorAt.IsZero() {
+		return StateClose, nil
+	}
+
+	errorExpireTTL := lastErrorAt.Add(m.currentOpenWindow()).Sub(time.Now().UTC())
 	if errorExpireTTL <= 0 {
 		return StateClose, m.Reset(ctx)
 	}
@@ -60,18 +204,111 @@ func (m *MemoryStorage) GetState(ctx context.Context) (State, error) {
 		return StateHalfOpen, nil
 	}
 
-	if m.failures.Load() >= m.options.FailureRateThreshold {
+	if m.reachedThreshold() {
 		return StateOpen, nil
 	}
 
 	return StateClose, nil
 }
 
-// Reset the state.
+// reachedThreshold reports if failures or slow calls reached the threshold.
+func (m *MemoryStorage) reachedThreshold() bool {
+	// in window mode, lastErrorAt is only stored when the failure rate reached the threshold.
+	if m.Windowed() || m.failures.Load() >= m.options.FailureRateThreshold {
+		return true
+	}
+
+	return m.options.SlowCallRateThreshold > 0 && m.slow.Load() >= m.options.SlowCallRateThreshold
+}
+
+// OpenRemaining is the time left until the circuit moves to half open state.
+func (m *MemoryStorage) OpenRemaining(ctx context.Context) (time.Duration, error) {
+	if m.currentOverride() == OverrideForceOpen {
+		return m.overrideRemaining(), nil
+	}
+
+	state, err := m.GetState(ctx)
+	if err != nil || state != StateOpen {
+		return 0, err
+	}
+
+	lastErrorAt := m.lastErrorAt.Load().(time.Time)
+	remaining := lastErrorAt.Add(m.currentOpenWindow() - m.options.HalfOpenWindow).Sub(time.Now().UTC())
+
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// SetOverride applies override until ttl is passed, if ttl is 0 it does not expire. OverrideNone clears it.
+func (m *MemoryStorage) SetOverride(ctx context.Context, override Override, ttl time.Duration) error {
+	m.overrideLock.Lock()
+	defer m.overrideLock.Unlock()
+
+	m.override = override
+	m.overrideUntil = time.Time{}
+
+	if ttl > 0 {
+		m.overrideUntil = time.Now().UTC().Add(ttl)
+	}
+
+	return nil
+}
+
+// GetOverride currently applied.
+func (m *MemoryStorage) GetOverride(ctx context.Context) (Override, error) {
+	return m.currentOverride(), nil
+}
+
+func (m *MemoryStorage) currentOverride() Override {
+	m.overrideLock.RLock()
+	defer m.overrideLock.RUnlock()
+
+	if !m.overrideUntil.IsZero() && !time.Now().UTC().Before(m.overrideUntil) {
+		return OverrideNone
+	}
+
+	return m.override
+}
+
+// overrideRemaining is the time left until override expires, it is zero if it does not expire.
+func (m *MemoryStorage) overrideRemaining() time.Duration {
+	m.overrideLock.RLock()
+	defer m.overrideLock.RUnlock()
+
+	if m.overrideUntil.IsZero() {
+		return 0
+	}
+
+	if remaining := m.overrideUntil.Sub(time.Now().UTC()); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+func (m *MemoryStorage) disabled() bool {
+	return m.currentOverride() == OverrideDisable
+}
+
+// Reset the state, the override is kept.
 func (m *MemoryStorage) Reset(ctx context.Context) error {
 	m.success.Store(0)
 	m.failures.Store(0)
+	m.slow.Store(0)
+	m.trials.Store(0)
+	m.trips.Store(0)
+	m.opened.Store(false)
+	m.openWindow.Store(int64(m.options.OpenWindow))
 	m.lastErrorAt.Store(time.Time{})
 
+	if m.Windowed() {
+		m.windowLock.Lock()
+		m.window.reset()
+		m.windowLock.Unlock()
+	}
+
 	return nil
 }
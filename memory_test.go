@@ -103,3 +103,304 @@ func TestMemoryStorageStorage_Reset(t *testing.T) {
 		assert.Equal(t, time.Time{}, ms.lastErrorAt.Load().(time.Time))
 	})
 }
+
+func TestMemoryStorage_SlidingWindow(t *testing.T) {
+	t.Run("failure rate is not reached, expect circuit to stay close", func(t *testing.T) {
+		ms := NewMemoryStorage(
+			WithOpenWindow(time.Minute),
+			WithFailureRateThreshold(50),
+			WithCountBasedSlidingWindow(4),
+			WithMinimumNumberOfCalls(4),
+		)
+
+		assert.Nil(t, ms.Success(context.Background(), 3))
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+	})
+
+	t.Run("minimum number of calls is not reached, expect circuit to stay close", func(t *testing.T) {
+		ms := NewMemoryStorage(
+			WithOpenWindow(time.Minute),
+			WithFailureRateThreshold(50),
+			WithCountBasedSlidingWindow(10),
+			WithMinimumNumberOfCalls(4),
+		)
+
+		assert.Nil(t, ms.Failure(context.Background(), 3))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+	})
+
+	t.Run("failure rate is reached, expect circuit to open", func(t *testing.T) {
+		ms := NewMemoryStorage(
+			WithOpenWindow(time.Minute),
+			WithFailureRateThreshold(50),
+			WithTimeBasedSlidingWindow(time.Minute),
+			WithMinimumNumberOfCalls(4),
+		)
+
+		assert.Nil(t, ms.Success(context.Background(), 2))
+		assert.Nil(t, ms.Failure(context.Background(), 2))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateOpen, cState)
+	})
+
+	t.Run("circuit is reset, expect window to be cleared", func(t *testing.T) {
+		ms := NewMemoryStorage(WithFailureRateThreshold(50), WithCountBasedSlidingWindow(4))
+
+		assert.Nil(t, ms.Success(context.Background(), 3))
+		assert.Nil(t, ms.Reset(context.Background()))
+
+		failures, _, total := ms.window.counts()
+		assert.Equal(t, int64(0), failures)
+		assert.Equal(t, int64(0), total)
+	})
+}
+
+func TestMemoryStorage_AcquireHalfOpen(t *testing.T) {
+	t.Run("trial calls are not limited, expect to always acquire", func(t *testing.T) {
+		ms := NewMemoryStorage()
+
+		for i := 0; i < 3; i++ {
+			acquired, err := ms.AcquireHalfOpen(context.Background())
+			assert.Nil(t, err)
+			assert.True(t, acquired)
+		}
+	})
+
+	t.Run("expect to only acquire permitted trial calls until next failure", func(t *testing.T) {
+		ms := NewMemoryStorage(WithPermittedCallsInHalfOpen(2))
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+
+		for _, expected := range []bool{true, true, false} {
+			acquired, err := ms.AcquireHalfOpen(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, expected, acquired)
+		}
+
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+
+		acquired, err := ms.AcquireHalfOpen(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, acquired)
+	})
+}
+
+func TestMemoryStorage_BackoffBelowThreshold(t *testing.T) {
+	ms := NewMemoryStorage(
+		WithOpenWindow(time.Minute),
+		WithHalfOpenWindow(time.Minute),
+		WithFailureRateThreshold(50),
+		WithOpenWindowBackoff(2, time.Hour),
+	)
+
+	// failures below threshold are all in half open tail of the window.
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+	}
+
+	assert.Equal(t, int64(0), ms.trips.Load())
+	assert.Equal(t, time.Minute, ms.currentOpenWindow())
+}
+
+func TestMemoryStorage_AcquireHalfOpenBelowThreshold(t *testing.T) {
+	ms := NewMemoryStorage(
+		WithFailureRateThreshold(50),
+		WithOpenWindow(time.Minute),
+		WithHalfOpenWindow(time.Minute),
+		WithPermittedCallsInHalfOpen(2),
+	)
+
+	// one failure below threshold, and circuit is in half open tail of its window.
+	assert.Nil(t, ms.Failure(context.Background(), 1))
+
+	cState, err := ms.GetState(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, StateHalfOpen, cState)
+
+	for i := 0; i < 10; i++ {
+		acquired, err := ms.AcquireHalfOpen(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, acquired, "trial calls are limited while circuit is not tripped")
+	}
+}
+
+func TestMemoryStorage_Slow(t *testing.T) {
+	t.Run("slow call rate threshold is not set, expect slow calls to be stored as failures", func(t *testing.T) {
+		ms := NewMemoryStorage(WithOpenWindow(time.Minute), WithFailureRateThreshold(2))
+
+		assert.Nil(t, ms.Slow(context.Background(), 2))
+		assert.Equal(t, int64(2), ms.failures.Load())
+		assert.Equal(t, int64(0), ms.slow.Load())
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateOpen, cState)
+	})
+
+	t.Run("slow call rate threshold is reached, expect circuit to open", func(t *testing.T) {
+		ms := NewMemoryStorage(WithOpenWindow(time.Minute), WithFailureRateThreshold(10), WithSlowCallRateThreshold(2))
+
+		assert.Nil(t, ms.Slow(context.Background(), 1))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+
+		assert.Nil(t, ms.Slow(context.Background(), 1))
+		assert.Equal(t, int64(0), ms.failures.Load())
+		assert.Equal(t, int64(2), ms.slow.Load())
+
+		cState, err = ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateOpen, cState)
+	})
+
+	t.Run("slow call rate of sliding window is reached, expect circuit to open", func(t *testing.T) {
+		ms := NewMemoryStorage(
+			WithOpenWindow(time.Minute),
+			WithFailureRateThreshold(50),
+			WithSlowCallRateThreshold(50),
+			WithCountBasedSlidingWindow(4),
+			WithMinimumNumberOfCalls(4),
+		)
+
+		assert.Nil(t, ms.Success(context.Background(), 2))
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+		assert.Nil(t, ms.Slow(context.Background(), 1))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+
+		assert.Nil(t, ms.Slow(context.Background(), 1))
+
+		cState, err = ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateOpen, cState)
+	})
+}
+
+func TestMemoryStorage_OpenRemaining(t *testing.T) {
+	ms := NewMemoryStorage(WithOpenWindow(time.Minute), WithHalfOpenWindow(10*time.Second), WithFailureRateThreshold(1))
+
+	remaining, err := ms.OpenRemaining(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), remaining)
+
+	assert.Nil(t, ms.Failure(context.Background(), 1))
+
+	remaining, err = ms.OpenRemaining(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, remaining > 49*time.Second && remaining <= 50*time.Second)
+}
+
+func TestMemoryStorage_Backoff(t *testing.T) {
+	ms := NewMemoryStorage(
+		WithOpenWindow(time.Minute),
+		WithHalfOpenWindow(10*time.Second),
+		WithFailureRateThreshold(1),
+		WithSuccessRateThreshold(1),
+		WithOpenWindowBackoff(2, 3*time.Minute),
+	)
+
+	// moveToHalfOpen moves last error time back, so circuit is in half open window.
+	moveToHalfOpen := func() {
+		ms.lastErrorAt.Store(time.Now().UTC().Add(-ms.currentOpenWindow() + 5*time.Second))
+	}
+
+	assert.Nil(t, ms.Failure(context.Background(), 1))
+	assert.Equal(t, time.Minute, ms.currentOpenWindow())
+
+	t.Run("expect each failure in half open state to multiply the open window up to max", func(t *testing.T) {
+		for _, expected := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+			moveToHalfOpen()
+
+			cState, err := ms.GetState(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, StateHalfOpen, cState)
+
+			assert.Nil(t, ms.Failure(context.Background(), 1))
+			assert.Equal(t, expected, ms.currentOpenWindow())
+		}
+
+		assert.Equal(t, int64(3), ms.trips.Load())
+	})
+
+	t.Run("circuit is closed, expect open window to be back to default", func(t *testing.T) {
+		moveToHalfOpen()
+		assert.Nil(t, ms.Success(context.Background(), 1))
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+
+		assert.Equal(t, int64(0), ms.trips.Load())
+		assert.Equal(t, time.Minute, ms.currentOpenWindow())
+	})
+}
+
+func TestMemoryStorage_Override(t *testing.T) {
+	ms := NewMemoryStorage(WithOpenWindow(time.Minute), WithHalfOpenWindow(10*time.Second), WithFailureRateThreshold(1))
+
+	t.Run("force open, expect open state until override is cleared", func(t *testing.T) {
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideForceOpen, 0))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateOpen, cState)
+
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideNone, 0))
+
+		cState, err = ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+	})
+
+	t.Run("force close, expect close state and failures to be stored", func(t *testing.T) {
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideForceClose, 0))
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+		assert.Equal(t, int64(1), ms.failures.Load())
+
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideNone, 0))
+
+		cState, err = ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateOpen, cState)
+		assert.Nil(t, ms.Reset(context.Background()))
+	})
+
+	t.Run("disable, expect failures to not be stored", func(t *testing.T) {
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideDisable, 0))
+		assert.Nil(t, ms.Failure(context.Background(), 1))
+		assert.Equal(t, int64(0), ms.failures.Load())
+
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideNone, 0))
+	})
+
+	t.Run("override with ttl, expect it to expire", func(t *testing.T) {
+		assert.Nil(t, ms.SetOverride(context.Background(), OverrideForceOpen, time.Minute))
+
+		remaining, err := ms.OpenRemaining(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, remaining > 59*time.Second && remaining <= time.Minute)
+
+		ms.overrideUntil = time.Now().UTC().Add(-time.Second)
+
+		override, err := ms.GetOverride(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, OverrideNone, override)
+
+		cState, err := ms.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, StateClose, cState)
+	})
+}
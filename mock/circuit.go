@@ -7,7 +7,7 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-var _ circuitbreaker.Manager = &Circuit{}
+var _ circuitbreaker.ContextManager = &Circuit{}
 
 type Circuit struct {
 	mock.Mock
@@ -23,6 +23,30 @@ func (c *Circuit) Do(ctx context.Context, fn circuitbreaker.Fn) (interface{}, er
 	return args.Get(0), args.Error(1)
 }
 
+func (c *Circuit) DoContext(ctx context.Context, fn circuitbreaker.ContextFn) (interface{}, error) {
+	args := c.Called(ctx, fn)
+
+	return args.Get(0), args.Error(1)
+}
+
+func (c *Circuit) DoWithFallback(ctx context.Context, fn circuitbreaker.Fn, fallback circuitbreaker.FallbackFn) (interface{}, error) {
+	args := c.Called(ctx, fn, fallback)
+
+	return args.Get(0), args.Error(1)
+}
+
+func (c *Circuit) DoContextWithFallback(
+	ctx context.Context, fn circuitbreaker.ContextFn, fallback circuitbreaker.FallbackFn,
+) (interface{}, error) {
+	args := c.Called(ctx, fn, fallback)
+
+	return args.Get(0), args.Error(1)
+}
+
+func (c *Circuit) LastState() circuitbreaker.State {
+	return c.Called().Get(0).(circuitbreaker.State)
+}
+
 func (c *Circuit) Done(ctx context.Context, err error) {
 	c.Called(ctx, err)
 }
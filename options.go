@@ -20,6 +20,23 @@ const (
 
 	// DefaultState is state that used fallback state in case of internal failure.
 	DefaultState State = StateClose
+
+	// DefaultMinimumNumberOfCalls is minimum number of calls in sliding window before failure rate is evaluated.
+	DefaultMinimumNumberOfCalls int64 = 10
+)
+
+// SlidingWindowType is how circuit failures are aggregated to decide about opening the circuit.
+type SlidingWindowType int64
+
+const (
+	// SlidingWindowNone compares the absolute failure count with FailureRateThreshold.
+	SlidingWindowNone SlidingWindowType = iota
+
+	// SlidingWindowCountBased compares failure percentage of the last SlidingWindowSize calls with FailureRateThreshold.
+	SlidingWindowCountBased
+
+	// SlidingWindowTimeBased compares failure percentage of the calls in last SlidingWindowDuration with FailureRateThreshold.
+	SlidingWindowTimeBased
 )
 
 // Options is circuit breaker options.
@@ -27,11 +44,46 @@ type Options struct {
 	Storage Storage
 	Logger  Logger
 	State   State
+	// SlowCallDuration is the duration that calls taking longer than it are considered slow
+	// if its 0, then call duration is not checked
+	SlowCallDuration time.Duration
+	// OnStateChange is called on each observed state change of circuit
+	OnStateChange OnStateChange
+	// CallTimeout is the duration that Do waits for a call before returning ErrTimeout
+	// if its 0, then calls are not timed out
+	CallTimeout time.Duration
+	// IgnoreTimeout is used to not store timed out calls as failures
+	IgnoreTimeout bool
+	// ErrorClassifier decides if an error is stored as failure, success or ignored
+	ErrorClassifier ErrorClassifier
+	// MaxConcurrentCalls is the number of calls that Do runs concurrently, the rest wait or are rejected with ErrBulkheadFull
+	// if its 0, then concurrent calls are not limited
+	MaxConcurrentCalls int64
+	// MaxWaitingCalls is the number of calls that wait for a slot when MaxConcurrentCalls are running
+	MaxWaitingCalls int64
+	// MaxWaitDuration is how long a call waits for a slot before it is rejected
+	// if its 0, then calls wait until a slot is freed or their ctx is done
+	MaxWaitDuration time.Duration
+	// BulkheadFailures is used to store calls rejected with ErrBulkheadFull as failures
+	BulkheadFailures bool
+	// RetryAttempts is the max number of attempts of a call in Do, including the first one
+	// if its 0 or 1, then calls are not retried
+	RetryAttempts int64
+	// RetryBackoff is the wait before the first retry, it is doubled for each next one
+	RetryBackoff time.Duration
+	// MaxRetryBackoff is the maximum wait between retries
+	// if its 0, then it is not limited
+	MaxRetryBackoff time.Duration
+	// RetryJitter is the fraction (0-1) of the wait between retries that is randomly cut from it
+	RetryJitter float64
+	// Retryable decides if a failed call is retried, by default errors that are stored as failure are retried
+	Retryable RetryableFunc
 }
 
 type StorageOptions struct {
 	Service string
 	// FailureRateThreshold haw many error to consider circuit as open
+	// if a sliding window is used, it is the failure percentage (0-100) of calls in window
 	FailureRateThreshold int64
 	// SuccessRateThreshold how much success to consider circuit as full close
 	// if its 0, then success counter will not change state to close and only timeBased solution will do it
@@ -40,6 +92,31 @@ type StorageOptions struct {
 	OpenWindow time.Duration
 	// HalfOpenWindow is the duration of circuit halfOpen state will last
 	HalfOpenWindow time.Duration
+	// SlidingWindowType is how failures are aggregated, default is SlidingWindowNone
+	SlidingWindowType SlidingWindowType
+	// SlidingWindowSize is number of calls in a count based sliding window
+	SlidingWindowSize int64
+	// SlidingWindowDuration is the duration of a time based sliding window
+	SlidingWindowDuration time.Duration
+	// MinimumNumberOfCalls is number of calls in sliding window before failure rate is evaluated
+	MinimumNumberOfCalls int64
+	// SlowCallRateThreshold how many slow call to consider circuit as open
+	// if a sliding window is used, it is the slow call percentage (0-100) of calls in window
+	// if its 0, then slow calls are stored as failures
+	SlowCallRateThreshold int64
+	// PermittedCallsInHalfOpen is number of trial calls allowed in each half open window
+	// if its 0, then all calls are allowed in half open state
+	PermittedCallsInHalfOpen int64
+	// PublishState is used to publish state changes to all instances sharing a distributed storage
+	PublishState bool
+	// OpenWindowMultiplier multiplies the open window on each consecutive failure in half open state
+	// if its 0 or 1, then open window is fixed
+	OpenWindowMultiplier float64
+	// MaxOpenWindow is the maximum of open window with backoff
+	// if its 0, then it is not limited
+	MaxOpenWindow time.Duration
+	// OpenWindowJitter is the fraction (0-1) of open window that is randomly cut from it, so instances do not retry together
+	OpenWindowJitter float64
 }
 
 func StorageWithDefaultOptions() StorageOption {
@@ -48,6 +125,7 @@ func StorageWithDefaultOptions() StorageOption {
 		o.HalfOpenWindow = DefaultHalfOpenWindow
 		o.FailureRateThreshold = DefaultFailureRateThreshold
 		o.SuccessRateThreshold = DefaultSuccessRateThreshold
+		o.MinimumNumberOfCalls = DefaultMinimumNumberOfCalls
 	}
 }
 
@@ -143,3 +221,171 @@ func WithHalfOpenWindow(duration time.Duration) StorageOption {
 		o.HalfOpenWindow = duration
 	}
 }
+
+// WithCountBasedSlidingWindow makes the circuit breaker evaluate the failure rate over the
+// last size calls instead of absolute failure count. In this mode FailureRateThreshold is
+// the percentage of failed calls in the window that opens the circuit. size is at least 1.
+func WithCountBasedSlidingWindow(size int64) StorageOption {
+	if size < 1 {
+		size = 1
+	}
+
+	return func(o *StorageOptions) {
+		o.SlidingWindowType = SlidingWindowCountBased
+		o.SlidingWindowSize = size
+	}
+}
+
+// WithTimeBasedSlidingWindow makes the circuit breaker evaluate the failure rate over the
+// calls of the last duration instead of absolute failure count. In this mode FailureRateThreshold
+// is the percentage of failed calls in the window that opens the circuit.
+func WithTimeBasedSlidingWindow(duration time.Duration) StorageOption {
+	return func(o *StorageOptions) {
+		o.SlidingWindowType = SlidingWindowTimeBased
+		o.SlidingWindowDuration = duration
+	}
+}
+
+// WithMinimumNumberOfCalls sets the number of calls that must be recorded in the sliding window
+// before the failure rate is evaluated, so a few failures at low traffic do not open the circuit.
+func WithMinimumNumberOfCalls(count int64) StorageOption {
+	return func(o *StorageOptions) {
+		o.MinimumNumberOfCalls = count
+	}
+}
+
+// WithPermittedCallsInHalfOpen limits the number of trial calls that pass through the circuit
+// in each half open window, the rest are rejected as if the circuit was open, so a recovering
+// service is not flooded. The outcome of trial calls decides about opening or closing the circuit,
+// so it should not be less than SuccessRateThreshold.
+func WithPermittedCallsInHalfOpen(count int64) StorageOption {
+	return func(o *StorageOptions) {
+		o.PermittedCallsInHalfOpen = count
+	}
+}
+
+// WithOpenWindowBackoff multiplies the open window on each consecutive failure in half open state, up to max.
+// the open window is back to OpenWindow when circuit is closed.
+func WithOpenWindowBackoff(multiplier float64, max time.Duration) StorageOption {
+	return func(o *StorageOptions) {
+		o.OpenWindowMultiplier = multiplier
+		o.MaxOpenWindow = max
+	}
+}
+
+// WithOpenWindowJitter randomly cut up to fraction (0-1) of the open window.
+func WithOpenWindowJitter(fraction float64) StorageOption {
+	return func(o *StorageOptions) {
+		o.OpenWindowJitter = fraction
+	}
+}
+
+// WithPublishState publish state changes of a distributed storage like redis, so instances sharing it
+// are notified immediately instead of on their next state check.
+func WithPublishState() StorageOption {
+	return func(o *StorageOptions) {
+		o.PublishState = true
+	}
+}
+
+// WithSlowCallDuration sets the duration that calls of Do taking longer than it are considered slow.
+// Slow calls are stored as failures, unless storage has a SlowCallRateThreshold that lets
+// them open the circuit independently.
+func WithSlowCallDuration(duration time.Duration) Option {
+	return func(o *Options) {
+		o.SlowCallDuration = duration
+	}
+}
+
+// WithSlowCallRateThreshold sets the threshold for slow calls that triggers the circuit breaker
+// to transition from a closed to an open state, independent of FailureRateThreshold.
+// It is the number of slow calls, or their percentage if a sliding window is used.
+func WithSlowCallRateThreshold(rate int64) StorageOption {
+	return func(o *StorageOptions) {
+		o.SlowCallRateThreshold = rate
+	}
+}
+
+// WithOnStateChange sets a callback that is called exactly once for each observed state change
+// of the circuit, including the ones made by other instances sharing the same storage.
+// The callback is called synchronously in the goroutine that observed the change, so it should be fast.
+func WithOnStateChange(fn OnStateChange) Option {
+	return func(o *Options) {
+		o.OnStateChange = fn
+	}
+}
+
+// WithCallTimeout sets the duration that Do and DoContext wait for a call to finish. After it, the ctx of
+// DoContext is canceled and ErrTimeout is returned, and the call is stored as a failure.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.CallTimeout = timeout
+	}
+}
+
+// WithIgnoreTimeout makes the circuit breaker to not store timed out calls as failures,
+// they still return ErrTimeout.
+func WithIgnoreTimeout() Option {
+	return func(o *Options) {
+		o.IgnoreTimeout = true
+	}
+}
+
+// WithBulkhead limits the calls of Do and DoContext that run concurrently to max, the rest are rejected
+// with ErrBulkheadFull, so a slow service does not saturate the caller. rejections are counted in Stat.
+func WithBulkhead(max int64) Option {
+	return func(o *Options) {
+		o.MaxConcurrentCalls = max
+	}
+}
+
+// WithBulkheadQueue lets up to size calls wait for a slot of bulkhead for timeout, instead of being rejected immediately.
+// if timeout is 0, calls wait until a slot is freed or their ctx is done.
+func WithBulkheadQueue(size int64, timeout time.Duration) Option {
+	return func(o *Options) {
+		o.MaxWaitingCalls = size
+		o.MaxWaitDuration = timeout
+	}
+}
+
+// WithBulkheadFailures stores calls rejected by bulkhead as failures, so a saturated service opens the circuit.
+func WithBulkheadFailures() Option {
+	return func(o *Options) {
+		o.BulkheadFailures = true
+	}
+}
+
+// WithRetry makes Do and DoContext to try a failed call again up to attempts times in total, waiting backoff
+// before the first retry and doubling it for each next one up to maxBackoff. circuit is checked before
+// every attempt, so retries stop as soon as it is open, and each attempt is stored on its own.
+// retries also stop when ctx is done or its deadline is before the next attempt.
+func WithRetry(attempts int64, backoff, maxBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.RetryAttempts = attempts
+		o.RetryBackoff = backoff
+		o.MaxRetryBackoff = maxBackoff
+	}
+}
+
+// WithRetryJitter randomly cut up to fraction (0-1) of the wait between retries, so callers do not retry together.
+func WithRetryJitter(fraction float64) Option {
+	return func(o *Options) {
+		o.RetryJitter = fraction
+	}
+}
+
+// WithRetryable sets the function that decides if a failed call is retried.
+// by default errors that are stored as failure by ErrorClassifier are retried.
+func WithRetryable(fn RetryableFunc) Option {
+	return func(o *Options) {
+		o.Retryable = fn
+	}
+}
+
+// WithErrorClassifier sets the policy that decides how errors of calls are stored by Do and Done,
+// as a failure, a success or ignored. By default all errors are failures.
+func WithErrorClassifier(classifier ErrorClassifier) Option {
+	return func(o *Options) {
+		o.ErrorClassifier = classifier
+	}
+}
@@ -0,0 +1,327 @@
+// Package otel instruments circuits with OpenTelemetry traces and metrics.
+package otel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/mrsoftware/circuitbreaker/otel"
+
+	serviceKey  = attribute.Key("circuitbreaker.service")
+	stateKey    = attribute.Key("circuitbreaker.state")
+	rejectedKey = attribute.Key("circuitbreaker.rejected")
+	fallbackKey = attribute.Key("circuitbreaker.fallback")
+	outcomeKey  = attribute.Key("circuitbreaker.outcome")
+
+	outcomeSuccess  = "success"
+	outcomeFailure  = "failure"
+	outcomeRejected = "rejected"
+)
+
+// Options is instrumentation options.
+type Options struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+}
+
+// Option configures the instrumentation.
+type Option func(*Options)
+
+// WithTracerProvider sets the tracer provider, global provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Options) {
+		o.TracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider, global provider is used by default.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(o *Options) {
+		o.MeterProvider = provider
+	}
+}
+
+var _ circuitbreaker.ContextManager = &Manager{}
+
+// lastStater is a manager that knows its last observed state without reading storage, like circuitbreaker.Circuit.
+type lastStater interface {
+	LastState() circuitbreaker.State
+}
+
+// Manager is a circuitbreaker.Manager that is instrumented with OpenTelemetry.
+type Manager struct {
+	circuitbreaker.Manager
+	service      string
+	attributes   attribute.Set
+	tracer       trace.Tracer
+	calls        metric.Int64Counter
+	registration metric.Registration
+	// state is the state of last exported stat, it is used if manager does not know its last observed state.
+	state atomic.Int64
+}
+
+// Wrap the manager of service, so its calls are traced and its stat is exported as metrics.
+// Call Close to stop exporting the stat when manager is not used anymore.
+func Wrap(service string, manager circuitbreaker.Manager, options ...Option) (*Manager, error) {
+	ops := Options{TracerProvider: otel.GetTracerProvider(), MeterProvider: otel.GetMeterProvider()}
+
+	for _, op := range options {
+		op(&ops)
+	}
+
+	wrapped := &Manager{
+		Manager:    manager,
+		service:    service,
+		attributes: attribute.NewSet(serviceKey.String(service)),
+		tracer:     ops.TracerProvider.Tracer(instrumentationName),
+	}
+
+	wrapped.state.Store(int64(circuitbreaker.StateUnknown))
+
+	if err := wrapped.instrument(ops.MeterProvider.Meter(instrumentationName)); err != nil {
+		return nil, err
+	}
+
+	return wrapped, nil
+}
+
+// instrument creates the metric instruments that mirror circuitbreaker.Stat.
+func (m *Manager) instrument(meter metric.Meter) (err error) {
+	m.calls, err = meter.Int64Counter("circuitbreaker.calls",
+		metric.WithDescription("Number of calls by their outcome, success, failure or rejected by open circuit."))
+	if err != nil {
+		return err
+	}
+
+	state, err := meter.Int64ObservableGauge("circuitbreaker.state",
+		metric.WithDescription("Current state of circuit, 0 is close, 1 is open and 2 is half open."))
+	if err != nil {
+		return err
+	}
+
+	failure, err := meter.Int64ObservableCounter("circuitbreaker.failure",
+		metric.WithDescription("Number of failed calls."))
+	if err != nil {
+		return err
+	}
+
+	success, err := meter.Int64ObservableCounter("circuitbreaker.success",
+		metric.WithDescription("Number of succeeded calls."))
+	if err != nil {
+		return err
+	}
+
+	slow, err := meter.Int64ObservableCounter("circuitbreaker.slow",
+		metric.WithDescription("Number of slow calls."))
+	if err != nil {
+		return err
+	}
+
+	fallback, err := meter.Int64ObservableCounter("circuitbreaker.fallback",
+		metric.WithDescription("Number of fallback calls."))
+	if err != nil {
+		return err
+	}
+
+	rejected, err := meter.Int64ObservableCounter("circuitbreaker.rejected",
+		metric.WithDescription("Number of calls rejected by bulkhead."))
+	if err != nil {
+		return err
+	}
+
+	degraded, err := meter.Int64ObservableGauge("circuitbreaker.degraded",
+		metric.WithDescription("1 if storage fell back to a less accurate storage, otherwise 0."))
+	if err != nil {
+		return err
+	}
+
+	m.registration, err = meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		stat := m.Manager.Stat(ctx)
+		attributes := metric.WithAttributeSet(m.attributes)
+
+		m.state.Store(int64(stat.State))
+
+		observer.ObserveInt64(state, int64(stat.State), attributes)
+		observer.ObserveInt64(failure, stat.Failure, attributes)
+		observer.ObserveInt64(success, stat.Success, attributes)
+		observer.ObserveInt64(slow, stat.Slow, attributes)
+		observer.ObserveInt64(fallback, stat.Fallback, attributes)
+		observer.ObserveInt64(rejected, stat.Rejected, attributes)
+		observer.ObserveInt64(degraded, boolInt(stat.Degraded), attributes)
+
+		return nil
+	}, state, failure, success, slow, fallback, rejected, degraded)
+
+	return err
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// Close stops exporting the stat of manager.
+func (m *Manager) Close() error {
+	return m.registration.Unregister()
+}
+
+// Do call fn through the wrapped manager in a span.
+func (m *Manager) Do(ctx context.Context, fn circuitbreaker.Fn) (interface{}, error) {
+	ctx, span := m.start(ctx, "circuitbreaker.Do")
+	defer span.End()
+
+	res, err := m.Manager.Do(ctx, fn)
+	m.end(ctx, span, err)
+
+	return res, err
+}
+
+// DoContext call fn through the wrapped manager in a span, fn get the ctx of span.
+func (m *Manager) DoContext(ctx context.Context, fn circuitbreaker.ContextFn) (interface{}, error) {
+	ctx, span := m.start(ctx, "circuitbreaker.DoContext")
+	defer span.End()
+
+	res, err := circuitbreaker.Execute(ctx, m.Manager, circuitbreaker.Func[interface{}](fn))
+	m.end(ctx, span, err)
+
+	return res, err
+}
+
+// DoWithFallback call fn through the wrapped manager in a span, fallback call is added as an event to the span.
+func (m *Manager) DoWithFallback(ctx context.Context, fn circuitbreaker.Fn, fallback circuitbreaker.FallbackFn) (interface{}, error) {
+	return m.withFallback(ctx, "circuitbreaker.DoWithFallback", fallback,
+		func(ctx context.Context, fallback circuitbreaker.FallbackFn) (interface{}, error) {
+			return circuitbreaker.ExecuteWithFallback(ctx, m.Manager,
+				func(context.Context) (interface{}, error) { return fn() }, fallback,
+			)
+		},
+	)
+}
+
+// DoContextWithFallback call fn through the wrapped manager in a span, fn get the ctx of span and
+// fallback call is added as an event to the span.
+func (m *Manager) DoContextWithFallback(
+	ctx context.Context, fn circuitbreaker.ContextFn, fallback circuitbreaker.FallbackFn,
+) (interface{}, error) {
+	return m.withFallback(ctx, "circuitbreaker.DoContextWithFallback", fallback,
+		func(ctx context.Context, fallback circuitbreaker.FallbackFn) (interface{}, error) {
+			return circuitbreaker.ExecuteWithFallback(ctx, m.Manager, circuitbreaker.Func[interface{}](fn), fallback)
+		},
+	)
+}
+
+// withFallback call do in a span named name, fallback call is added as an event to the span.
+func (m *Manager) withFallback(
+	ctx context.Context, name string, fallback circuitbreaker.FallbackFn,
+	do func(ctx context.Context, fallback circuitbreaker.FallbackFn) (interface{}, error),
+) (interface{}, error) {
+	ctx, span := m.start(ctx, name)
+	defer span.End()
+
+	var reason error
+
+	if fallback != nil {
+		next := fallback
+		fallback = func(ctx context.Context, err error) (interface{}, error) {
+			reason = err
+			span.AddEvent("circuitbreaker.fallback", trace.WithAttributes(outcomeKey.String(m.outcome(err))))
+
+			return next(ctx, err)
+		}
+	}
+
+	// reason is only set if fallback is called, otherwise err is result of fn.
+	res, err := do(ctx, fallback)
+	if reason == nil {
+		reason = err
+	}
+
+	span.SetAttributes(fallbackKey.Bool(fallback != nil && reason != nil))
+	m.end(ctx, span, reason)
+
+	return res, err
+}
+
+// start a span with state of circuit at entry, it is the last observed state so storage is not read for it.
+func (m *Manager) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return m.tracer.Start(ctx, name, trace.WithAttributes(
+		serviceKey.String(m.service),
+		stateKey.String(circuitbreaker.GetStateText(m.lastState())),
+	))
+}
+
+// lastState of manager, or the state of last exported stat if manager does not know it.
+func (m *Manager) lastState() circuitbreaker.State {
+	if manager, ok := m.Manager.(lastStater); ok {
+		return manager.LastState()
+	}
+
+	return circuitbreaker.State(m.state.Load())
+}
+
+// end set the outcome of call to span and count it.
+func (m *Manager) end(ctx context.Context, span trace.Span, err error) {
+	outcome := m.outcome(err)
+
+	span.SetAttributes(rejectedKey.Bool(outcome == outcomeRejected), outcomeKey.String(outcome))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	m.count(ctx, outcome)
+}
+
+// IsAvailable checks the wrapped manager and count the rejection.
+func (m *Manager) IsAvailable(ctx context.Context) bool {
+	available := m.Manager.IsAvailable(ctx)
+	if !available {
+		m.count(ctx, outcomeRejected)
+		trace.SpanFromContext(ctx).AddEvent("circuitbreaker.rejected", trace.WithAttributes(serviceKey.String(m.service)))
+	}
+
+	return available
+}
+
+// Done report the result to wrapped manager and add it as an event to the span of ctx.
+func (m *Manager) Done(ctx context.Context, err error) {
+	m.Manager.Done(ctx, err)
+
+	outcome := m.outcome(err)
+
+	trace.SpanFromContext(ctx).AddEvent("circuitbreaker.done", trace.WithAttributes(
+		serviceKey.String(m.service),
+		outcomeKey.String(outcome),
+	))
+
+	m.count(ctx, outcome)
+}
+
+func (m *Manager) outcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.Is(err, circuitbreaker.ErrIsOpen), errors.Is(err, circuitbreaker.ErrBulkheadFull):
+		return outcomeRejected
+	}
+
+	return outcomeFailure
+}
+
+func (m *Manager) count(ctx context.Context, outcome string) {
+	m.calls.Add(ctx, 1, metric.WithAttributes(serviceKey.String(m.service), outcomeKey.String(outcome)))
+}
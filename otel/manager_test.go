@@ -0,0 +1,179 @@
+package otel_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/mrsoftware/circuitbreaker/otel"
+	"github.com/stretchr/testify/assert"
+	mockPkg "github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestManager_Do(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	manager := &mock.Circuit{}
+
+	wrapped, err := otel.Wrap("test", manager,
+		otel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		otel.WithMeterProvider(sdkmetric.NewMeterProvider()),
+	)
+	assert.Nil(t, err)
+
+	fn := func() (interface{}, error) { return nil, nil }
+	mockFn := mockPkg.AnythingOfType("circuitbreaker.Fn")
+
+	t.Run("call is rejected, expect span to have rejected attribute and state at entry", func(t *testing.T) {
+		manager.On("LastState").Return(circuitbreaker.StateOpen).Once()
+		manager.On("Do", mockPkg.Anything, mockFn).Return(nil, circuitbreaker.ErrIsOpen).Once()
+
+		_, err := wrapped.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "circuitbreaker.Do", spans[0].Name())
+		assert.Subset(t, spans[0].Attributes(), []attribute.KeyValue{
+			attribute.String("circuitbreaker.service", "test"),
+			attribute.String("circuitbreaker.state", "Open"),
+			attribute.Bool("circuitbreaker.rejected", true),
+			attribute.String("circuitbreaker.outcome", "rejected"),
+		})
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("call is failed, expect span to record the error", func(t *testing.T) {
+		expectedErr := errors.New("some error")
+
+		manager.On("LastState").Return(circuitbreaker.StateClose).Once()
+		manager.On("Do", mockPkg.Anything, mockFn).Return(nil, expectedErr).Once()
+
+		_, err := wrapped.Do(context.Background(), fn)
+		assert.Equal(t, expectedErr, err)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		assert.Subset(t, spans[1].Attributes(), []attribute.KeyValue{
+			attribute.Bool("circuitbreaker.rejected", false),
+			attribute.String("circuitbreaker.outcome", "failure"),
+		})
+		assert.Len(t, spans[1].Events(), 1)
+
+		manager.AssertExpectations(t)
+	})
+}
+
+func TestManager_DoWithFallback(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	storage := circuitbreaker.NewMemoryStorage(
+		circuitbreaker.WithFailureRateThreshold(1),
+		circuitbreaker.StorageWithDefaultOptions(),
+	)
+
+	wrapped, err := otel.Wrap("test", circuitbreaker.NewCircuit(circuitbreaker.WithStorage(storage)),
+		otel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		otel.WithMeterProvider(sdkmetric.NewMeterProvider()),
+	)
+	assert.Nil(t, err)
+
+	t.Run("service call is failed, expect span to have fallback attribute and event", func(t *testing.T) {
+		response, err := wrapped.DoWithFallback(context.Background(),
+			func() (interface{}, error) { return nil, errors.New("some error") },
+			func(ctx context.Context, err error) (interface{}, error) { return "fallback", nil },
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "fallback", response)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "circuitbreaker.DoWithFallback", spans[0].Name())
+		assert.Subset(t, spans[0].Attributes(), []attribute.KeyValue{
+			attribute.Bool("circuitbreaker.fallback", true),
+			attribute.String("circuitbreaker.outcome", "failure"),
+		})
+		assert.Equal(t, "circuitbreaker.fallback", spans[0].Events()[0].Name)
+	})
+}
+
+func TestManager_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	storage := circuitbreaker.NewMemoryStorage(circuitbreaker.StorageWithDefaultOptions())
+
+	wrapped, err := otel.Wrap("test", circuitbreaker.NewCircuit(circuitbreaker.WithStorage(storage)),
+		otel.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	assert.Nil(t, err)
+
+	wrapped.Done(context.Background(), nil)
+	wrapped.Done(context.Background(), errors.New("some error"))
+
+	t.Run("expect stat to be exported", func(t *testing.T) {
+		metrics := collect(t, reader)
+
+		assert.Equal(t, int64(1), metrics["circuitbreaker.failure"])
+		assert.Equal(t, int64(1), metrics["circuitbreaker.success"])
+		assert.Equal(t, int64(0), metrics["circuitbreaker.slow"])
+		assert.Equal(t, int64(0), metrics["circuitbreaker.fallback"])
+		assert.Equal(t, int64(0), metrics["circuitbreaker.rejected"])
+		assert.Equal(t, int64(0), metrics["circuitbreaker.degraded"])
+		assert.Subset(t, keys(metrics), []string{"circuitbreaker.fallback", "circuitbreaker.rejected", "circuitbreaker.degraded"})
+		assert.Equal(t, int64(circuitbreaker.StateClose), metrics["circuitbreaker.state"])
+		assert.Equal(t, int64(2), metrics["circuitbreaker.calls"])
+	})
+
+	t.Run("manager is closed, expect stat to not be exported", func(t *testing.T) {
+		assert.Nil(t, wrapped.Close())
+
+		metrics := collect(t, reader)
+
+		assert.NotContains(t, metrics, "circuitbreaker.failure")
+		assert.Contains(t, metrics, "circuitbreaker.calls")
+	})
+}
+
+// collect the sum of data points of each int64 metric.
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]int64 {
+	var data metricdata.ResourceMetrics
+
+	assert.Nil(t, reader.Collect(context.Background(), &data))
+
+	metrics := make(map[string]int64)
+
+	for _, scope := range data.ScopeMetrics {
+		for _, item := range scope.Metrics {
+			// sums and gauges have the same data points layout.
+			var points struct {
+				DataPoints []struct{ Value int64 }
+			}
+
+			raw, err := json.Marshal(item.Data)
+			assert.Nil(t, err)
+			assert.Nil(t, json.Unmarshal(raw, &points))
+
+			for _, point := range points.DataPoints {
+				metrics[item.Name] += point.Value
+			}
+		}
+	}
+
+	return metrics
+}
+
+func keys(metrics map[string]int64) []string {
+	names := make([]string, 0, len(metrics))
+
+	for name := range metrics {
+		names = append(names, name)
+	}
+
+	return names
+}
@@ -0,0 +1,106 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOverrideNotSupported meant storage of circuit does not implement OverrideStorage.
+var ErrOverrideNotSupported = errors.New("CircuitBreaker: storage does not support overrides")
+
+// Override is a manual override of circuit state.
+type Override int64
+
+const (
+	// OverrideNone mean circuit state is decided by its storage.
+	OverrideNone Override = iota
+
+	// OverrideForceOpen mean circuit is open and reject all calls.
+	OverrideForceOpen
+
+	// OverrideForceClose mean circuit is close and accept all calls, outcomes are still stored.
+	OverrideForceClose
+
+	// OverrideDisable mean circuit accept all calls and outcomes are not stored, only counted in Stat.
+	OverrideDisable
+)
+
+const (
+	overrideNoneText       = "None"
+	overrideForceOpenText  = "ForceOpen"
+	overrideForceCloseText = "ForceClose"
+	overrideDisableText    = "Disable"
+)
+
+// GetOverrideText of circuit breaker.
+func GetOverrideText(override Override) string {
+	switch override {
+	case OverrideNone:
+		return overrideNoneText
+	case OverrideForceOpen:
+		return overrideForceOpenText
+	case OverrideForceClose:
+		return overrideForceCloseText
+	case OverrideDisable:
+		return overrideDisableText
+	}
+
+	return stateNotValidText
+}
+
+// ForceOpen the circuit, so all calls are rejected until ttl is passed or override is cleared.
+// if ttl is 0, it does not expire.
+func (s *Circuit) ForceOpen(ctx context.Context, ttl time.Duration) error {
+	return s.setOverride(ctx, OverrideForceOpen, ttl)
+}
+
+// ForceClose the circuit, so all calls are accepted until ttl is passed or override is cleared.
+// if ttl is 0, it does not expire.
+func (s *Circuit) ForceClose(ctx context.Context, ttl time.Duration) error {
+	return s.setOverride(ctx, OverrideForceClose, ttl)
+}
+
+// Disable the circuit, so all calls pass through and their outcomes are only counted in Stat,
+// until ttl is passed or override is cleared. if ttl is 0, it does not expire.
+func (s *Circuit) Disable(ctx context.Context, ttl time.Duration) error {
+	return s.setOverride(ctx, OverrideDisable, ttl)
+}
+
+// ClearOverride so circuit state is decided by its storage again.
+func (s *Circuit) ClearOverride(ctx context.Context) error {
+	return s.setOverride(ctx, OverrideNone, 0)
+}
+
+// Override currently applied to the circuit.
+func (s *Circuit) Override(ctx context.Context) Override {
+	storage, ok := s.ops.Storage.(OverrideStorage)
+	if !ok {
+		return OverrideNone
+	}
+
+	override, err := storage.GetOverride(ctx)
+	if err != nil {
+		s.ops.Logger.Error(fmt.Errorf("getting override: %w", err))
+
+		return OverrideNone
+	}
+
+	return override
+}
+
+func (s *Circuit) setOverride(ctx context.Context, override Override, ttl time.Duration) error {
+	storage, ok := s.ops.Storage.(OverrideStorage)
+	if !ok {
+		return ErrOverrideNotSupported
+	}
+
+	if err := storage.SetOverride(ctx, override, ttl); err != nil {
+		return fmt.Errorf("setting override: %w", err)
+	}
+
+	s.refresh(ctx)
+
+	return nil
+}
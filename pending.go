@@ -0,0 +1,62 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// pendingResults are call results that are not written to storage yet, they are used by storage decorators
+// to batch results or to keep them while storage is failing. it is concurrent safe.
+type pendingResults struct {
+	failures atomic.Int64
+	slow     atomic.Int64
+	success  atomic.Int64
+}
+
+// failing reports if there are pending failures or slow calls, that may change the state of storage.
+func (p *pendingResults) failing() bool {
+	return p.failures.Load() > 0 || p.slow.Load() > 0
+}
+
+// reset drops pending results.
+func (p *pendingResults) reset() {
+	p.failures.Store(0)
+	p.slow.Store(0)
+	p.success.Store(0)
+}
+
+// flush writes pending results to storage, successes are written first.
+// results that failed to be written are kept for the next flush, and it reports if any result is written.
+func (p *pendingResults) flush(ctx context.Context, storage Storage) (written bool, err error) {
+	if delta := p.success.Swap(0); delta > 0 {
+		if err := storage.Success(ctx, delta); err != nil {
+			p.success.Add(delta)
+
+			return written, err
+		}
+
+		written = true
+	}
+
+	if delta := p.slow.Swap(0); delta > 0 {
+		if err := slowCall(ctx, storage, delta); err != nil {
+			p.slow.Add(delta)
+
+			return written, err
+		}
+
+		written = true
+	}
+
+	if delta := p.failures.Swap(0); delta > 0 {
+		if err := storage.Failure(ctx, delta); err != nil {
+			p.failures.Add(delta)
+
+			return written, err
+		}
+
+		written = true
+	}
+
+	return written, nil
+}
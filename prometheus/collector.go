@@ -0,0 +1,331 @@
+// Package prometheus exports circuits stat as prometheus metrics.
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	serviceLabel = "service"
+	resultLabel  = "result"
+	fromLabel    = "from"
+	toLabel      = "to"
+
+	resultSuccess  = "success"
+	resultFailure  = "failure"
+	resultRejected = "rejected"
+)
+
+// Options is collector options.
+type Options struct {
+	Namespace string
+	Buckets   []float64
+}
+
+// Option configures the collector.
+type Option func(*Options)
+
+// WithNamespace sets the namespace that is prefixed to all metric names.
+func WithNamespace(namespace string) Option {
+	return func(o *Options) {
+		o.Namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of call latency histogram in seconds.
+func WithBuckets(buckets []float64) Option {
+	return func(o *Options) {
+		o.Buckets = buckets
+	}
+}
+
+// subscriber is a Manager that notifies its state changes, like circuitbreaker.Circuit.
+type subscriber interface {
+	Subscribe(buffer int) (<-chan circuitbreaker.StateChange, func())
+}
+
+var _ stdprometheus.Collector = &Collector{}
+
+// Collector is a prometheus collector that exports metrics of wrapped circuits.
+// Metrics are labelled by service, the same name that is used for circuitbreaker.WithServiceName.
+type Collector struct {
+	state       *stdprometheus.Desc
+	calls       *stdprometheus.CounterVec
+	latency     *stdprometheus.HistogramVec
+	transitions *stdprometheus.CounterVec
+	fallbacks   *stdprometheus.CounterVec
+
+	lock     sync.RWMutex
+	managers map[string]*Manager
+}
+
+// NewCollector create new instance of Collector, it should be registered to a prometheus registerer.
+func NewCollector(options ...Option) *Collector {
+	ops := Options{Namespace: "circuitbreaker", Buckets: stdprometheus.DefBuckets}
+
+	for _, op := range options {
+		op(&ops)
+	}
+
+	return &Collector{
+		state: stdprometheus.NewDesc(
+			stdprometheus.BuildFQName(ops.Namespace, "", "state"),
+			"Current state of circuit, 0 is close, 1 is open and 2 is half open.",
+			[]string{serviceLabel}, nil,
+		),
+		calls: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: ops.Namespace,
+			Name:      "calls_total",
+			Help:      "Number of calls by their result, success, failure or rejected by open circuit.",
+		}, []string{serviceLabel, resultLabel}),
+		latency: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: ops.Namespace,
+			Name:      "call_duration_seconds",
+			Help:      "Latency of calls that passed through the circuit.",
+			Buckets:   ops.Buckets,
+		}, []string{serviceLabel}),
+		transitions: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: ops.Namespace,
+			Name:      "state_transitions_total",
+			Help:      "Number of circuit state transitions.",
+		}, []string{serviceLabel, fromLabel, toLabel}),
+		fallbacks: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: ops.Namespace,
+			Name:      "fallbacks_total",
+			Help:      "Number of fallback calls.",
+		}, []string{serviceLabel}),
+		managers: make(map[string]*Manager),
+	}
+}
+
+// Wrap the manager of service, so its calls are measured by collector.
+// if a manager is already wrapped for the service, it is replaced.
+func (c *Collector) Wrap(service string, manager circuitbreaker.Manager) *Manager {
+	wrapped := &Manager{Manager: manager, service: service, collector: c, state: circuitbreaker.StateUnknown}
+
+	if sub, ok := manager.(subscriber); ok {
+		var events <-chan circuitbreaker.StateChange
+
+		events, wrapped.unsubscribe = sub.Subscribe(100)
+
+		go func() {
+			for event := range events {
+				c.transition(service, event.From, event.To)
+			}
+		}()
+	}
+
+	c.lock.Lock()
+	previous := c.managers[service]
+	c.managers[service] = wrapped
+	c.lock.Unlock()
+
+	if previous != nil {
+		previous.stop()
+	}
+
+	return wrapped
+}
+
+// Remove the wrapped manager of service from collector.
+func (c *Collector) Remove(service string) {
+	c.lock.Lock()
+	manager := c.managers[service]
+	delete(c.managers, service)
+	c.lock.Unlock()
+
+	if manager != nil {
+		manager.stop()
+	}
+
+	c.calls.DeletePartialMatch(stdprometheus.Labels{serviceLabel: service})
+	c.latency.DeletePartialMatch(stdprometheus.Labels{serviceLabel: service})
+	c.transitions.DeletePartialMatch(stdprometheus.Labels{serviceLabel: service})
+	c.fallbacks.DeletePartialMatch(stdprometheus.Labels{serviceLabel: service})
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *stdprometheus.Desc) {
+	ch <- c.state
+	c.calls.Describe(ch)
+	c.latency.Describe(ch)
+	c.transitions.Describe(ch)
+	c.fallbacks.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- stdprometheus.Metric) {
+	c.lock.RLock()
+	managers := make([]*Manager, 0, len(c.managers))
+
+	for _, manager := range c.managers {
+		managers = append(managers, manager)
+	}
+	c.lock.RUnlock()
+
+	for _, manager := range managers {
+		state := manager.Manager.Stat(context.Background()).State
+		manager.observe(state)
+
+		ch <- stdprometheus.MustNewConstMetric(c.state, stdprometheus.GaugeValue, float64(state), manager.service)
+	}
+
+	c.calls.Collect(ch)
+	c.latency.Collect(ch)
+	c.transitions.Collect(ch)
+	c.fallbacks.Collect(ch)
+}
+
+func (c *Collector) transition(service string, from, to circuitbreaker.State) {
+	c.transitions.WithLabelValues(service, circuitbreaker.GetStateText(from), circuitbreaker.GetStateText(to)).Inc()
+}
+
+var _ circuitbreaker.ContextManager = &Manager{}
+
+// Manager is a circuitbreaker.Manager that is measured by Collector.
+type Manager struct {
+	circuitbreaker.Manager
+	service     string
+	collector   *Collector
+	unsubscribe func()
+
+	lock  sync.Mutex
+	state circuitbreaker.State
+}
+
+// Do call fn through the wrapped manager and measure it.
+func (m *Manager) Do(ctx context.Context, fn circuitbreaker.Fn) (interface{}, error) {
+	start := time.Now()
+
+	res, err := m.Manager.Do(ctx, fn)
+	m.measure(start, err)
+
+	return res, err
+}
+
+// DoContext call fn through the wrapped manager and measure it.
+func (m *Manager) DoContext(ctx context.Context, fn circuitbreaker.ContextFn) (interface{}, error) {
+	start := time.Now()
+
+	res, err := circuitbreaker.Execute(ctx, m.Manager, circuitbreaker.Func[interface{}](fn))
+	m.measure(start, err)
+
+	return res, err
+}
+
+// DoWithFallback call fn through the wrapped manager and measure it and its fallback.
+func (m *Manager) DoWithFallback(ctx context.Context, fn circuitbreaker.Fn, fallback circuitbreaker.FallbackFn) (interface{}, error) {
+	return m.withFallback(fallback, func(fallback circuitbreaker.FallbackFn) (interface{}, error) {
+		return circuitbreaker.ExecuteWithFallback(ctx, m.Manager,
+			func(context.Context) (interface{}, error) { return fn() }, fallback,
+		)
+	})
+}
+
+// DoContextWithFallback call fn through the wrapped manager and measure it and its fallback.
+func (m *Manager) DoContextWithFallback(
+	ctx context.Context, fn circuitbreaker.ContextFn, fallback circuitbreaker.FallbackFn,
+) (interface{}, error) {
+	return m.withFallback(fallback, func(fallback circuitbreaker.FallbackFn) (interface{}, error) {
+		return circuitbreaker.ExecuteWithFallback(ctx, m.Manager, circuitbreaker.Func[interface{}](fn), fallback)
+	})
+}
+
+// withFallback measure the call of do and its fallback.
+func (m *Manager) withFallback(
+	fallback circuitbreaker.FallbackFn, do func(fallback circuitbreaker.FallbackFn) (interface{}, error),
+) (interface{}, error) {
+	var reason error
+
+	if fallback != nil {
+		next := fallback
+		fallback = func(ctx context.Context, err error) (interface{}, error) {
+			reason = err
+			m.collector.fallbacks.WithLabelValues(m.service).Inc()
+
+			return next(ctx, err)
+		}
+	}
+
+	start := time.Now()
+
+	// reason is only set if fallback is called, otherwise err is result of fn.
+	res, err := do(fallback)
+	if reason == nil {
+		reason = err
+	}
+
+	m.measure(start, reason)
+
+	return res, err
+}
+
+// measure the call that is started at start and its result is err.
+func (m *Manager) measure(start time.Time, err error) {
+	if errors.Is(err, circuitbreaker.ErrIsOpen) || errors.Is(err, circuitbreaker.ErrBulkheadFull) {
+		m.result(resultRejected)
+
+		return
+	}
+
+	m.collector.latency.WithLabelValues(m.service).Observe(time.Since(start).Seconds())
+	m.done(err)
+}
+
+// IsAvailable checks the wrapped manager and count the rejection.
+func (m *Manager) IsAvailable(ctx context.Context) bool {
+	available := m.Manager.IsAvailable(ctx)
+	if !available {
+		m.result(resultRejected)
+	}
+
+	return available
+}
+
+// Done report the result to wrapped manager and count it.
+func (m *Manager) Done(ctx context.Context, err error) {
+	m.Manager.Done(ctx, err)
+	m.done(err)
+}
+
+func (m *Manager) done(err error) {
+	if err != nil {
+		m.result(resultFailure)
+
+		return
+	}
+
+	m.result(resultSuccess)
+}
+
+func (m *Manager) result(result string) {
+	m.collector.calls.WithLabelValues(m.service, result).Inc()
+}
+
+// observe state on collect, transitions are only counted this way if manager does not notify state changes.
+func (m *Manager) observe(state circuitbreaker.State) {
+	if m.unsubscribe != nil || state == circuitbreaker.StateUnknown {
+		return
+	}
+
+	m.lock.Lock()
+	from := m.state
+	m.state = state
+	m.lock.Unlock()
+
+	if from != state && from != circuitbreaker.StateUnknown {
+		m.collector.transition(m.service, from, state)
+	}
+}
+
+func (m *Manager) stop() {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
+}
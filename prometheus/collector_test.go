@@ -0,0 +1,118 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/mrsoftware/circuitbreaker/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	mockPkg "github.com/stretchr/testify/mock"
+)
+
+func TestCollector_Wrap(t *testing.T) {
+	t.Run("expect calls of wrapped manager to be counted by result", func(t *testing.T) {
+		collector := prometheus.NewCollector()
+		manager := &mock.Circuit{}
+		wrapped := collector.Wrap("test", manager)
+
+		fn := func() (interface{}, error) { return nil, nil }
+		mockFn := mockPkg.AnythingOfType("circuitbreaker.Fn")
+
+		manager.On("Do", context.Background(), mockFn).Return("response", nil).Once()
+		manager.On("Do", context.Background(), mockFn).Return(nil, errors.New("some error")).Once()
+		manager.On("Do", context.Background(), mockFn).Return(nil, circuitbreaker.ErrIsOpen).Once()
+		manager.On("Done", context.Background(), nil).Once()
+		manager.On("Stat", context.Background()).Return(circuitbreaker.Stat{State: circuitbreaker.StateOpen})
+
+		_, _ = wrapped.Do(context.Background(), fn)
+		_, _ = wrapped.Do(context.Background(), fn)
+		_, _ = wrapped.Do(context.Background(), fn)
+		wrapped.Done(context.Background(), nil)
+
+		expected := `
+# HELP circuitbreaker_calls_total Number of calls by their result, success, failure or rejected by open circuit.
+# TYPE circuitbreaker_calls_total counter
+circuitbreaker_calls_total{result="failure",service="test"} 1
+circuitbreaker_calls_total{result="rejected",service="test"} 1
+circuitbreaker_calls_total{result="success",service="test"} 2
+# HELP circuitbreaker_state Current state of circuit, 0 is close, 1 is open and 2 is half open.
+# TYPE circuitbreaker_state gauge
+circuitbreaker_state{service="test"} 1
+`
+		err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "circuitbreaker_calls_total", "circuitbreaker_state")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "circuitbreaker_call_duration_seconds"))
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("expect state transitions of circuit to be counted", func(t *testing.T) {
+		collector := prometheus.NewCollector(prometheus.WithNamespace("test"))
+		registry := stdprometheus.NewRegistry()
+		registry.MustRegister(collector)
+
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(1),
+			circuitbreaker.WithOpenWindow(time.Minute),
+		)
+
+		wrapped := collector.Wrap("test", circuitbreaker.NewCircuit(
+			circuitbreaker.WithStorage(storage),
+			circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		))
+
+		assert.True(t, wrapped.IsAvailable(context.Background()))
+		wrapped.Done(context.Background(), errors.New("some error"))
+		assert.False(t, wrapped.IsAvailable(context.Background()))
+
+		expected := `
+# HELP test_state_transitions_total Number of circuit state transitions.
+# TYPE test_state_transitions_total counter
+test_state_transitions_total{from="Close",service="test",to="Open"} 1
+`
+		assert.Eventually(t, func() bool {
+			return testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_state_transitions_total") == nil
+		}, time.Second, 10*time.Millisecond)
+
+		collector.Remove("test")
+		assert.Equal(t, 0, testutil.CollectAndCount(collector, "test_state_transitions_total"))
+	})
+}
+func TestManager_DoWithFallback(t *testing.T) {
+	t.Run("circuit is open, expect rejection and fallback to be counted", func(t *testing.T) {
+		collector := prometheus.NewCollector()
+		storage := circuitbreaker.NewMemoryStorage(
+			circuitbreaker.WithFailureRateThreshold(1),
+			circuitbreaker.WithOpenWindow(time.Minute),
+		)
+
+		wrapped := collector.Wrap("test", circuitbreaker.NewCircuit(circuitbreaker.WithStorage(storage)))
+		wrapped.Done(context.Background(), errors.New("some error"))
+
+		response, err := wrapped.DoWithFallback(context.Background(),
+			func() (interface{}, error) { return "response", nil },
+			func(ctx context.Context, err error) (interface{}, error) { return "fallback", nil },
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "fallback", response)
+
+		expected := `
+# HELP circuitbreaker_calls_total Number of calls by their result, success, failure or rejected by open circuit.
+# TYPE circuitbreaker_calls_total counter
+circuitbreaker_calls_total{result="failure",service="test"} 1
+circuitbreaker_calls_total{result="rejected",service="test"} 1
+# HELP circuitbreaker_fallbacks_total Number of fallback calls.
+# TYPE circuitbreaker_fallbacks_total counter
+circuitbreaker_fallbacks_total{service="test"} 1
+`
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "circuitbreaker_calls_total", "circuitbreaker_fallbacks_total")
+		assert.Nil(t, err)
+	})
+}
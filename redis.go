@@ -3,19 +3,36 @@ package circuitbreaker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
 const (
 	failuresField = "failures"
-	successField  = "success"
+	slowField     = "slow"
+
+	windowSuffix   = ":window"
+	eventsSuffix   = ":events"
+	overrideSuffix = ":override"
 )
 
-var _ Storage = &RedisStorage{}
+var (
+	_ Storage           = &RedisStorage{}
+	_ WindowedStorage   = &RedisStorage{}
+	_ HalfOpenLimiter   = &RedisStorage{}
+	_ SlowCallStorage   = &RedisStorage{}
+	_ OpenWindowStorage = &RedisStorage{}
+	_ StateNotifier     = &RedisStorage{}
+	_ OverrideStorage   = &RedisStorage{}
+	_ OptionsStorage    = &RedisStorage{}
+)
 
 // NewRedisStorage create new instance of RedisStorage.
-func NewRedisStorage(client *redis.Client, options ...StorageOption) *RedisStorage {
+// client can be a single node, cluster, sentinel failover or ring client.
+func NewRedisStorage(client redis.UniversalClient, options ...StorageOption) *RedisStorage {
 	storage := RedisStorage{client: client}
 
 	for _, op := range options {
@@ -23,55 +40,75 @@ func NewRedisStorage(client *redis.Client, options ...StorageOption) *RedisStora
 	}
 
 	storage.serviceKey = namespace(storage.options.Service)
+	storage.windowKey = storage.serviceKey + windowSuffix
+	storage.overrideKey = storage.serviceKey + overrideSuffix
+
+	if storage.options.PublishState {
+		storage.channel = storage.serviceKey + eventsSuffix
+	}
 
 	return &storage
 }
 
 // RedisStorage is redis based storage for circuit breaker and is concurrent safe.
 type RedisStorage struct {
-	client     *redis.Client
-	options    StorageOptions
-	serviceKey string
+	client      redis.UniversalClient
+	options     StorageOptions
+	serviceKey  string
+	windowKey   string
+	overrideKey string
+	// channel is where state changes are published, it is empty if they are not published.
+	channel   string
+	listeners stateListeners
+	// lock guards subscribed and closed, the subscription is shared between storages of client, see subscribeState.
+	lock       sync.Mutex
+	subscribed bool
+	closed     bool
+}
+
+// StorageOptions of storage.
+func (r *RedisStorage) StorageOptions() StorageOptions {
+	return r.options
+}
+
+// Windowed reports if storage use a sliding window.
+func (r *RedisStorage) Windowed() bool {
+	return r.options.SlidingWindowType != SlidingWindowNone
 }
 
 // Failure is responsible to store failures.
 func (r *RedisStorage) Failure(ctx context.Context, delta int64) error {
+	return r.trip(ctx, failuresField, delta)
+}
+
+// Slow is responsible to store slow calls, they are stored as failures if SlowCallRateThreshold is 0.
+func (r *RedisStorage) Slow(ctx context.Context, delta int64) error {
+	if r.options.SlowCallRateThreshold <= 0 {
+		return r.Failure(ctx, delta)
+	}
 
-	pipe := r.client.Pipeline()
-	pipe.HIncrBy(ctx, r.serviceKey, failuresField, delta)
-	pipe.HDel(ctx, r.serviceKey, successField)
-	pipe.Expire(ctx, r.serviceKey, r.options.OpenWindow)
+	return r.trip(ctx, slowField, delta)
+}
 
-	return r.pipeExec(ctx, pipe)
+// trip increment the field and start a new open window, in window mode only if the rate reached the threshold.
+func (r *RedisStorage) trip(ctx context.Context, field string, delta int64) error {
+	return failureScript.Run(ctx, r.client, r.keys(), r.args(field, delta)...).Err()
 }
 
 // Success is responsible to store success.
 func (r *RedisStorage) Success(ctx context.Context, delta int64) error {
-	sCount, err := r.client.HIncrBy(ctx, r.serviceKey, successField, delta).Result()
-	if err != nil {
-		return err
-	}
-
-	if sCount >= r.options.SuccessRateThreshold {
-		return r.Reset(ctx)
-	}
-
-	return nil
+	return successScript.Run(ctx, r.client, r.keys(), r.args(delta)...).Err()
 }
 
-func (r *RedisStorage) pipeExec(ctx context.Context, pipe redis.Pipeliner) error {
-	cmdErrs, err := pipe.Exec(ctx)
-	if err != nil {
-		return err
+// AcquireHalfOpen reports if one more trial call is permitted in current half open window.
+func (r *RedisStorage) AcquireHalfOpen(ctx context.Context) (bool, error) {
+	if r.options.PermittedCallsInHalfOpen <= 0 {
+		return true, nil
 	}
 
-	for _, cmdErr := range cmdErrs {
-		if er := cmdErr.Err(); er != nil {
-			return err
-		}
-	}
+	acquired, err := acquireScript.Run(ctx, r.client, r.keys(), r.args()...).Int64()
 
-	return nil
+	return acquired == 1, err
 }
 
 // GetState current state.
@@ -79,46 +116,132 @@ func (r *RedisStorage) pipeExec(ctx context.Context, pipe redis.Pipeliner) error
 // if we are in halfOpen window == halfOpen
 // if key exist and not in halfOpen window and errors count reached the limit == open.
 func (r *RedisStorage) GetState(ctx context.Context) (State, error) {
-	duration, err := r.client.PTTL(ctx, r.serviceKey).Result()
+	state, _, err := r.state(ctx)
+
+	return state, err
+}
+
+// OpenRemaining is the time left until the circuit moves to half open state.
+func (r *RedisStorage) OpenRemaining(ctx context.Context) (time.Duration, error) {
+	state, remaining, err := r.state(ctx)
+	if err != nil || state != StateOpen {
+		return 0, err
+	}
+
+	return remaining, nil
+}
+
+// state and the remaining time of open state, read atomically.
+func (r *RedisStorage) state(ctx context.Context) (State, time.Duration, error) {
+	values, err := stateScript.Run(ctx, r.client, r.keys(), r.args()...).Int64Slice()
 	if err != nil {
-		return StateClose, err
+		return StateClose, 0, err
 	}
 
-	// -1, -2 means no expire and key not exist and
-	if duration < 0 {
-		return StateClose, nil
+	if len(values) != 2 {
+		return StateClose, 0, fmt.Errorf("unexpected state script result: %v", values)
 	}
 
-	if duration <= r.options.HalfOpenWindow {
-		return StateHalfOpen, nil
+	return State(values[0]), time.Duration(values[1]) * time.Millisecond, nil
+}
+
+func (r *RedisStorage) keys() []string {
+	return []string{r.serviceKey, r.windowKey, r.overrideKey}
+}
+
+// args of scripts, the storage options and then the operation arguments.
+func (r *RedisStorage) args(args ...interface{}) []interface{} {
+	width := r.options.SlidingWindowDuration / windowBuckets
+	if width <= 0 {
+		width = 1
 	}
 
-	reachRateLimit, err := r.reachRateLimit(ctx)
-	if err != nil {
-		return StateClose, err
+	return append([]interface{}{
+		r.options.FailureRateThreshold,
+		r.options.SuccessRateThreshold,
+		r.options.OpenWindow.Milliseconds(),
+		r.options.HalfOpenWindow.Milliseconds(),
+		int64(r.options.SlidingWindowType),
+		r.options.SlidingWindowSize,
+		r.options.SlidingWindowDuration.Milliseconds(),
+		r.options.MinimumNumberOfCalls,
+		r.options.SlowCallRateThreshold,
+		r.options.PermittedCallsInHalfOpen,
+		time.Now().UnixNano() / int64(width),
+		r.channel,
+		r.options.OpenWindowMultiplier,
+		r.options.MaxOpenWindow.Milliseconds(),
+		jitter(r.options),
+	}, args...)
+}
+
+// NotifyState calls fn with the new state on each change made by any instance, if PublishState is set.
+// if subscription drops, changes are still seen on state checks, and the state is read again when it is back.
+func (r *RedisStorage) NotifyState(fn func(state State)) func() {
+	if r.channel == "" {
+		return func() {}
 	}
 
-	if reachRateLimit {
-		return StateOpen, nil
+	remove := r.listeners.add(fn)
+	r.subscribe()
+
+	var once sync.Once
+
+	return func() { once.Do(remove) }
+}
+
+func (r *RedisStorage) subscribe() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.subscribed || r.closed {
+		return
 	}
 
-	return StateClose, nil
+	r.subscribed = true
+
+	subscribeState(r)
 }
 
-func (r *RedisStorage) reachRateLimit(ctx context.Context) (bool, error) {
-	fCount, err := r.client.HGet(ctx, r.serviceKey, failuresField).Int64()
-	if errors.Is(err, redis.Nil) {
-		return false, nil
+// refresh notifies listeners with the current state.
+func (r *RedisStorage) refresh() {
+	if state, err := r.GetState(context.Background()); err == nil {
+		r.listeners.notify(state)
 	}
+}
 
-	if err != nil {
-		return false, err
+// Close the state change subscription, the shared subscription of client is closed when its last storage is closed.
+func (r *RedisStorage) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.closed = true
+
+	if !r.subscribed {
+		return nil
+	}
+
+	r.subscribed = false
+
+	return unsubscribeState(r)
+}
+
+// SetOverride applies override for all instances until ttl is passed, if ttl is 0 it does not expire. OverrideNone clears it.
+func (r *RedisStorage) SetOverride(ctx context.Context, override Override, ttl time.Duration) error {
+	return overrideScript.Run(ctx, r.client, r.keys(), r.args(int64(override), ttl.Milliseconds())...).Err()
+}
+
+// GetOverride currently applied.
+func (r *RedisStorage) GetOverride(ctx context.Context) (Override, error) {
+	override, err := r.client.Get(ctx, r.overrideKey).Int64()
+	if errors.Is(err, redis.Nil) {
+		return OverrideNone, nil
 	}
 
-	return fCount >= r.options.FailureRateThreshold, nil
+	return Override(override), err
 }
 
-// Reset storage.
+// Reset storage, the override is kept.
 func (r *RedisStorage) Reset(ctx context.Context) error {
-	return r.client.Del(ctx, r.serviceKey).Err()
+	return r.client.Del(ctx, r.serviceKey, r.windowKey).Err()
 }
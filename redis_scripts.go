@@ -0,0 +1,325 @@
+package circuitbreaker
+
+import (
+	"github.com/go-redis/redis/v8"
+)
+
+// scripts of RedisStorage, each storage operation is a single atomic script.
+// KEYS[1] is the service key, KEYS[2] is the sliding window key and KEYS[3] is the override key.
+// ARGV[1..15] are the storage options that are passed by RedisStorage.args, and the rest are the operation arguments.
+
+// scriptPrelude parse the options and defines the shared functions.
+const scriptPrelude = `
+local failureThreshold = tonumber(ARGV[1])
+local successThreshold = tonumber(ARGV[2])
+local openWindow = tonumber(ARGV[3])
+local halfOpenWindow = tonumber(ARGV[4])
+local windowType = tonumber(ARGV[5])
+local windowSize = tonumber(ARGV[6])
+local windowDuration = tonumber(ARGV[7])
+local minimumCalls = tonumber(ARGV[8])
+local slowThreshold = tonumber(ARGV[9])
+local permittedTrials = tonumber(ARGV[10])
+local bucket = ARGV[11]
+local channel = ARGV[12]
+local multiplier = tonumber(ARGV[13])
+local maxOpenWindow = tonumber(ARGV[14])
+local jitter = tonumber(ARGV[15])
+
+-- backoffWindow is the open window after trips consecutive failures in half open state.
+local function backoffWindow(trips)
+	local window = openWindow
+
+	if multiplier > 1 and trips > 0 then
+		window = openWindow * multiplier ^ trips
+	end
+
+	if maxOpenWindow > 0 and window > maxOpenWindow then
+		window = maxOpenWindow
+	end
+
+	return math.floor(window - window * jitter)
+end
+
+local function exceedRate(failures, slow, total)
+	if total == 0 or total < minimumCalls then
+		return false
+	end
+
+	if slowThreshold > 0 and slow * 100 >= slowThreshold * total then
+		return true
+	end
+
+	return failures * 100 >= failureThreshold * total
+end
+
+local function push(outcome, count)
+	for _ = 1, math.min(count, windowSize) do
+		redis.call('RPUSH', KEYS[2], outcome)
+	end
+end
+
+local function recordCountWindow(failures, slow, success)
+	push('s', success)
+	push('d', slow)
+	push('f', failures)
+	redis.call('LTRIM', KEYS[2], -windowSize, -1)
+
+	local counts = {f = 0, d = 0, s = 0}
+	local items = redis.call('LRANGE', KEYS[2], 0, -1)
+
+	for _, item in ipairs(items) do
+		counts[item] = (counts[item] or 0) + 1
+	end
+
+	return counts.f, counts.d, #items
+end
+
+local function recordTimeWindow(failures, slow, success)
+	local deltas = {f = failures, d = slow, s = success}
+
+	for outcome, delta in pairs(deltas) do
+		if delta > 0 then
+			redis.call('HINCRBY', KEYS[2], bucket .. ':' .. outcome, delta)
+		end
+	end
+
+	redis.call('PEXPIRE', KEYS[2], windowDuration)
+
+	local counts = {f = 0, d = 0, s = 0}
+	local current = tonumber(bucket)
+	local fields = redis.call('HGETALL', KEYS[2])
+
+	for i = 1, #fields, 2 do
+		local index, outcome = string.match(fields[i], '^(%d+):(%a)$')
+
+		if index == nil or current - tonumber(index) >= 10 then
+			redis.call('HDEL', KEYS[2], fields[i])
+		else
+			counts[outcome] = (counts[outcome] or 0) + tonumber(fields[i + 1])
+		end
+	end
+
+	return counts.f, counts.d, counts.f + counts.d + counts.s
+end
+
+-- record outcomes in sliding window and report if failure or slow call rate reached the threshold.
+local function record(failures, slow, success)
+	local f, d, total = 0, 0, 0
+
+	if windowType == 1 then
+		f, d, total = recordCountWindow(failures, slow, success)
+	elseif windowType == 2 then
+		f, d, total = recordTimeWindow(failures, slow, success)
+	end
+
+	return exceedRate(f, d, total)
+end
+
+-- tripped reports if circuit is in open or half open window.
+local function tripped()
+	return redis.call('EXISTS', KEYS[1]) == 1
+end
+
+-- reachedThreshold reports if failures or slow calls in service key reached the threshold.
+local function reachedThreshold()
+	-- in window mode, service key is only stored when the failure rate reached the threshold.
+	if windowType ~= 0 then
+		return true
+	end
+
+	local counts = redis.call('HMGET', KEYS[1], 'failures', 'slow')
+
+	if (tonumber(counts[1]) or 0) >= failureThreshold then
+		return true
+	end
+
+	return slowThreshold > 0 and (tonumber(counts[2]) or 0) >= slowThreshold
+end
+
+-- opened reports if circuit is really tripped, not only has a failure below the threshold in service key.
+local function opened()
+	return redis.call('HGET', KEYS[1], 'opened') == '1'
+end
+
+-- override is the manual override, 0 is none, 1 is force open, 2 is force close and 3 is disable.
+local function override()
+	return tonumber(redis.call('GET', KEYS[3])) or 0
+end
+
+-- storedState returns the stored state, 0 is close, 1 is open and 2 is half open, and the ttl of open window.
+local function storedState()
+	local ttl = redis.call('PTTL', KEYS[1])
+
+	if ttl < 0 then
+		return 0, ttl
+	end
+
+	if ttl <= halfOpenWindow then
+		return 2, ttl
+	end
+
+	if reachedThreshold() then
+		return 1, ttl
+	end
+
+	return 0, ttl
+end
+
+-- currentState returns the state with override applied, and the remaining time of open state in milliseconds.
+local function currentState()
+	local o = override()
+
+	if o == 1 then
+		return 1, math.max(redis.call('PTTL', KEYS[3]), 0)
+	end
+
+	if o ~= 0 then
+		return 0, 0
+	end
+
+	local state, ttl = storedState()
+
+	if state == 1 then
+		return state, math.max(ttl - halfOpenWindow, 0)
+	end
+
+	return state, 0
+end
+
+-- publish the state to channel if it is changed from the state before.
+local function publish(before)
+	if channel == '' then
+		return
+	end
+
+	local after = currentState()
+
+	if after ~= before then
+		redis.call('PUBLISH', channel, after)
+	end
+end
+`
+
+// failureScript store failures or slow calls, ARGV[16] is the field and ARGV[17] is the delta.
+// in window mode, circuit is only tripped when the rate reached the threshold.
+// opened field is set when the threshold is reached, so trial calls are only limited after it,
+// and each consecutive failure in half open state after it is counted in trips field, to back off the open window.
+// nolint:gochecknoglobals
+var failureScript = redis.NewScript(scriptPrelude + `
+local field = ARGV[16]
+local delta = tonumber(ARGV[17])
+
+if override() == 3 then
+	return 0
+end
+
+local before = currentState()
+local stored = storedState()
+
+if windowType ~= 0 and not tripped() then
+	local reached
+
+	if field == 'slow' then
+		reached = record(0, delta, 0)
+	else
+		reached = record(delta, 0, 0)
+	end
+
+	if not reached then
+		return 0
+	end
+
+	-- window is frozen while circuit is tripped, and starts over after it.
+	redis.call('DEL', KEYS[2])
+end
+
+local trips = tonumber(redis.call('HGET', KEYS[1], 'trips')) or 0
+
+if stored == 2 and opened() then
+	trips = redis.call('HINCRBY', KEYS[1], 'trips', 1)
+end
+
+redis.call('HINCRBY', KEYS[1], field, delta)
+
+if reachedThreshold() then
+	redis.call('HSET', KEYS[1], 'opened', 1)
+end
+redis.call('HDEL', KEYS[1], 'success', 'trials')
+redis.call('PEXPIRE', KEYS[1], backoffWindow(trips))
+publish(before)
+
+return 1
+`)
+
+// successScript store successes, ARGV[16] is the delta. circuit is reset when SuccessRateThreshold is reached.
+// nolint:gochecknoglobals
+var successScript = redis.NewScript(scriptPrelude + `
+local delta = tonumber(ARGV[16])
+
+if override() == 3 then
+	return 0
+end
+
+if not tripped() then
+	if windowType ~= 0 then
+		record(0, 0, delta)
+	end
+
+	return 0
+end
+
+local before = currentState()
+
+if redis.call('HINCRBY', KEYS[1], 'success', delta) >= successThreshold then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	publish(before)
+
+	return 1
+end
+
+return 0
+`)
+
+// stateScript returns the state and the remaining time of open state in milliseconds.
+// nolint:gochecknoglobals
+var stateScript = redis.NewScript(scriptPrelude + `
+local state, remaining = currentState()
+
+return {state, remaining}
+`)
+
+// overrideScript sets the override, ARGV[16] is the override and ARGV[17] is its ttl in milliseconds, 0 is without expiry.
+// nolint:gochecknoglobals
+var overrideScript = redis.NewScript(scriptPrelude + `
+local value = tonumber(ARGV[16])
+local ttl = tonumber(ARGV[17])
+local before = currentState()
+
+if value == 0 then
+	redis.call('DEL', KEYS[3])
+elseif ttl > 0 then
+	redis.call('SET', KEYS[3], value, 'PX', ttl)
+else
+	redis.call('SET', KEYS[3], value)
+end
+
+publish(before)
+
+return 1
+`)
+
+// acquireScript reports if one more trial call is permitted in current half open window,
+// trials are only limited after circuit is really tripped.
+// nolint:gochecknoglobals
+var acquireScript = redis.NewScript(scriptPrelude + `
+if permittedTrials <= 0 or not opened() then
+	return 1
+end
+
+if redis.call('HINCRBY', KEYS[1], 'trials', 1) <= permittedTrials then
+	return 1
+end
+
+return 0
+`)
@@ -0,0 +1,166 @@
+package circuitbreaker
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// eventsPattern is the pattern of state change channels of all services.
+const eventsPattern = storagePrefix + "{*}" + eventsSuffix
+
+// redisSubscribers are the state change subscriptions that are shared between storages, one for each client.
+var redisSubscribers = struct {
+	lock        sync.Mutex
+	subscribers map[subscriberKey]*redisSubscriber
+}{subscribers: make(map[subscriberKey]*redisSubscriber)}
+
+// subscriberKey is the client and the pattern or channel of a shared subscription.
+type subscriberKey struct {
+	client redis.UniversalClient
+	topic  string
+}
+
+// redisSubscriber is a subscription to state changes that routes each change to storages of its service.
+type redisSubscriber struct {
+	pubsub   *redis.PubSub
+	lock     sync.RWMutex
+	storages map[string]map[*RedisStorage]struct{}
+	// subscribed is set once the subscription is made, storages that are added after it read current state themselves.
+	subscribed bool
+}
+
+// subscribeState adds storage to the shared subscription of its client, the subscription is made by the first storage.
+// a ring client does not share it between services, as each service publishes on its own shard.
+func subscribeState(storage *RedisStorage) {
+	redisSubscribers.lock.Lock()
+	defer redisSubscribers.lock.Unlock()
+
+	key := subscriberKeyOf(storage)
+
+	subscriber, ok := redisSubscribers.subscribers[key]
+	if !ok {
+		subscriber = &redisSubscriber{storages: make(map[string]map[*RedisStorage]struct{})}
+
+		if key.topic == eventsPattern {
+			subscriber.pubsub = storage.client.PSubscribe(context.Background(), key.topic)
+		} else {
+			subscriber.pubsub = storage.client.Subscribe(context.Background(), key.topic)
+		}
+
+		redisSubscribers.subscribers[key] = subscriber
+
+		go subscriber.listen()
+	}
+
+	subscriber.lock.Lock()
+	defer subscriber.lock.Unlock()
+
+	if subscriber.storages[storage.channel] == nil {
+		subscriber.storages[storage.channel] = make(map[*RedisStorage]struct{})
+	}
+
+	subscriber.storages[storage.channel][storage] = struct{}{}
+
+	if subscriber.subscribed {
+		go storage.refresh()
+	}
+}
+
+// unsubscribeState removes storage from the shared subscription of its client, and closes it if it is the last one.
+func unsubscribeState(storage *RedisStorage) error {
+	redisSubscribers.lock.Lock()
+	defer redisSubscribers.lock.Unlock()
+
+	key := subscriberKeyOf(storage)
+
+	subscriber, ok := redisSubscribers.subscribers[key]
+	if !ok {
+		return nil
+	}
+
+	subscriber.lock.Lock()
+	delete(subscriber.storages[storage.channel], storage)
+
+	if len(subscriber.storages[storage.channel]) == 0 {
+		delete(subscriber.storages, storage.channel)
+	}
+
+	empty := len(subscriber.storages) == 0
+	subscriber.lock.Unlock()
+
+	if !empty {
+		return nil
+	}
+
+	delete(redisSubscribers.subscribers, key)
+
+	return subscriber.pubsub.Close()
+}
+
+func subscriberKeyOf(storage *RedisStorage) subscriberKey {
+	if _, ok := storage.client.(*redis.Ring); ok {
+		return subscriberKey{client: storage.client, topic: storage.channel}
+	}
+
+	return subscriberKey{client: storage.client, topic: eventsPattern}
+}
+
+func (s *redisSubscriber) listen() {
+	for message := range s.pubsub.ChannelWithSubscriptions(context.Background(), 100) {
+		switch m := message.(type) {
+		case *redis.Subscription:
+			if m.Kind != "subscribe" && m.Kind != "psubscribe" {
+				continue
+			}
+
+			// subscribed, or subscribed again after a drop, so changes that are missed in between are read now.
+			for _, storage := range s.made() {
+				storage.refresh()
+			}
+		case *redis.Message:
+			state, err := strconv.ParseInt(m.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			for _, storage := range s.storagesOf(m.Channel) {
+				storage.listeners.notify(State(state))
+			}
+		}
+	}
+}
+
+// made marks the subscription as made, and returns all storages.
+func (s *redisSubscriber) made() []*RedisStorage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.subscribed = true
+
+	var storages []*RedisStorage
+
+	for _, subscribed := range s.storages {
+		for storage := range subscribed {
+			storages = append(storages, storage)
+		}
+	}
+
+	return storages
+}
+
+// storagesOf channel.
+func (s *redisSubscriber) storagesOf(channel string) []*RedisStorage {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	storages := make([]*RedisStorage, 0, len(s.storages[channel]))
+
+	for storage := range s.storages[channel] {
+		storages = append(storages, storage)
+	}
+
+	return storages
+}
@@ -6,15 +6,17 @@ import (
 	"testing"
 	"time"
 
-	"github.com/go-redis/redismock/v8"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
 	"github.com/mrsoftware/circuitbreaker"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	tempkey       = "circuitBreaker:test"
+	tempkey       = "circuitBreaker:{test}"
 	failuresField = "failures"
 	successField  = "success"
+	trialsField   = "trials"
 	stateField    = "state"
 )
 
@@ -32,37 +34,48 @@ var (
 )
 
 func TestRedisStorage_GetStatus(t *testing.T) {
-	redisClient, mock := redismock.NewClientMock()
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
 	rs := circuitbreaker.NewRedisStorage(redisClient, options...)
 
 	t.Run("key expired or not exits == close", func(t *testing.T) {
-		mock.ExpectPTTL(tempkey).SetVal(-1)
-
 		state, err := rs.GetState(context.Background())
 		assert.Nil(t, err)
 		assert.Equal(t, circuitbreaker.StateClose, state)
 	})
 
+	t.Run("key exist and not in halfOpen window and errors count reached the limit == open", func(t *testing.T) {
+		server.FlushAll()
+		assert.Nil(t, rs.Failure(context.Background(), failureRateThreshold))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+	})
+
 	t.Run("we are in halfOpen window == halfOpen", func(t *testing.T) {
-		mock.ExpectPTTL(tempkey).SetVal(time.Second * 20)
+		server.FlushAll()
+		assert.Nil(t, rs.Failure(context.Background(), failureRateThreshold))
+		server.FastForward(circuitbreaker.DefaultOpenWindow - circuitbreaker.DefaultHalfOpenWindow)
 
 		state, err := rs.GetState(context.Background())
 		assert.Nil(t, err)
 		assert.Equal(t, circuitbreaker.StateHalfOpen, state)
 	})
 
-	t.Run("key exist and not in halfOpen window and errors count reached the limit == open", func(t *testing.T) {
-		mock.ExpectPTTL(tempkey).SetVal(time.Second * 40)
-		mock.ExpectHGet(tempkey, failuresField).SetVal(strconv.Itoa(int(failureRateThreshold)))
+	t.Run("key exist and not in halfOpen window and errors count not reached the limit == close", func(t *testing.T) {
+		server.FlushAll()
+		assert.Nil(t, rs.Failure(context.Background(), failureRateThreshold-1))
 
 		state, err := rs.GetState(context.Background())
 		assert.Nil(t, err)
-		assert.Equal(t, circuitbreaker.StateOpen, state)
+		assert.Equal(t, circuitbreaker.StateClose, state)
 	})
 
-	t.Run("key exist and not in halfOpen window and reachRateLimit got redis nil == close", func(t *testing.T) {
-		mock.ExpectPTTL(tempkey).SetVal(time.Second * 40)
-		mock.ExpectHGet(tempkey, failuresField).RedisNil()
+	t.Run("open window is expired == close", func(t *testing.T) {
+		server.FlushAll()
+		assert.Nil(t, rs.Failure(context.Background(), failureRateThreshold))
+		server.FastForward(circuitbreaker.DefaultOpenWindow)
 
 		state, err := rs.GetState(context.Background())
 		assert.Nil(t, err)
@@ -71,37 +84,694 @@ func TestRedisStorage_GetStatus(t *testing.T) {
 }
 
 func TestRedisStorage_Failure(t *testing.T) {
-	redisClient, mock := redismock.NewClientMock()
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
 	rs := circuitbreaker.NewRedisStorage(redisClient, options...)
 
 	t.Run("incr failure count to change state to open", func(t *testing.T) {
-
-		mock.ExpectHIncrBy(tempkey, failuresField, 2).SetVal(2)
-		mock.ExpectHDel(tempkey, successField).SetVal(2)
-		mock.ExpectExpire(tempkey, circuitbreaker.DefaultOpenWindow).SetVal(true)
+		server.HSet(tempkey, successField, "1")
+		server.HSet(tempkey, trialsField, "1")
 
 		err := rs.Failure(context.Background(), 2)
 		assert.Nil(t, err)
 
-		mock.ExpectPTTL(tempkey).SetVal(time.Second * 40)
-		mock.ExpectHGet(tempkey, failuresField).SetVal(strconv.Itoa(int(failureRateThreshold)))
+		assert.Equal(t, "2", server.HGet(tempkey, failuresField))
+		assert.Equal(t, "", server.HGet(tempkey, successField))
+		assert.Equal(t, "", server.HGet(tempkey, trialsField))
+		assert.Equal(t, circuitbreaker.DefaultOpenWindow, server.TTL(tempkey))
 
 		state, err := rs.GetState(context.Background())
 		assert.Nil(t, err)
 		assert.Equal(t, circuitbreaker.StateOpen, state)
 	})
-
 }
 
 func TestRedisStorage_Success(t *testing.T) {
-	redisClient, mock := redismock.NewClientMock()
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
 	rs := circuitbreaker.NewRedisStorage(redisClient, options...)
 
+	t.Run("circuit is close, expect to not store success", func(t *testing.T) {
+		err := rs.Success(context.Background(), 1)
+		assert.Nil(t, err)
+		assert.False(t, server.Exists(tempkey))
+	})
+
 	t.Run("incr success count to change state to close", func(t *testing.T) {
-		mock.ExpectHIncrBy(tempkey, successField, 1).SetVal(1)
+		assert.Nil(t, rs.Failure(context.Background(), failureRateThreshold))
 
 		err := rs.Success(context.Background(), 1)
 		assert.Nil(t, err)
+		assert.Equal(t, "1", server.HGet(tempkey, successField))
+
+		err = rs.Success(context.Background(), successRateThreshold-1)
+		assert.Nil(t, err)
+		assert.False(t, server.Exists(tempkey))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+	})
+}
+
+func TestRedisStorage_ScriptCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient, options...)
+
+	t.Run("scripts are flushed from server, expect them to be loaded again", func(t *testing.T) {
+		_, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+
+		assert.Nil(t, redisClient.ScriptFlush(context.Background()).Err())
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+	})
+}
+
+func TestRedisStorage_SlidingWindow(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	windowOptions := append(options[:len(options):len(options)],
+		circuitbreaker.WithFailureRateThreshold(50),
+		circuitbreaker.WithMinimumNumberOfCalls(4),
+	)
+
+	t.Run("failure rate is not reached, expect circuit to stay close", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(windowOptions, circuitbreaker.WithCountBasedSlidingWindow(4))...)
+
+		assert.Nil(t, rs.Success(context.Background(), 3))
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+	})
+
+	t.Run("count based failure rate is reached, expect circuit to open", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(windowOptions, circuitbreaker.WithCountBasedSlidingWindow(4))...)
+
+		assert.Nil(t, rs.Success(context.Background(), 4))
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err = rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+	})
+
+	t.Run("time based failure rate is reached, expect circuit to open", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(windowOptions, circuitbreaker.WithTimeBasedSlidingWindow(time.Minute))...)
+
+		assert.Nil(t, rs.Success(context.Background(), 2))
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err = rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+	})
+
+	t.Run("window size is zero, expect it to be one call like memory storage", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(options[:len(options):len(options)],
+			circuitbreaker.WithFailureRateThreshold(50),
+			circuitbreaker.WithCountBasedSlidingWindow(0),
+		)...)
+
+		assert.Nil(t, rs.Success(context.Background(), 3))
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+	})
+
+	t.Run("circuit is reset, expect window to be cleared", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(windowOptions, circuitbreaker.WithCountBasedSlidingWindow(4))...)
+
+		assert.Nil(t, rs.Success(context.Background(), 3))
+		assert.Nil(t, rs.Reset(context.Background()))
+		assert.False(t, server.Exists(tempkey+":window"))
+	})
+}
+
+func TestRedisStorage_AcquireHalfOpen(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient, append(options, circuitbreaker.WithPermittedCallsInHalfOpen(2))...)
+
+	t.Run("expect to only acquire permitted trial calls until next failure", func(t *testing.T) {
+		assert.Nil(t, rs.Failure(context.Background(), 2))
+
+		for _, expected := range []bool{true, true, false} {
+			acquired, err := rs.AcquireHalfOpen(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, expected, acquired)
+		}
+
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		acquired, err := rs.AcquireHalfOpen(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, acquired)
+	})
+
+	t.Run("circuit key is expired, expect to acquire and not keep the key", func(t *testing.T) {
+		server.FlushAll()
+
+		acquired, err := rs.AcquireHalfOpen(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, acquired)
+		assert.False(t, server.Exists(tempkey))
+	})
+}
+
+func TestRedisStorage_BackoffBelowThreshold(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient,
+		circuitbreaker.WithServiceName(serviceName),
+		circuitbreaker.WithFailureRateThreshold(50),
+		circuitbreaker.WithOpenWindow(time.Minute),
+		circuitbreaker.WithHalfOpenWindow(time.Minute),
+		circuitbreaker.WithOpenWindowBackoff(2, time.Hour),
+	)
+
+	// failures below threshold are all in half open tail of the window.
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+	}
+
+	assert.Equal(t, "", server.HGet(tempkey, "trips"))
+	assert.Equal(t, time.Minute, server.TTL(tempkey))
+}
+
+func TestRedisStorage_AcquireHalfOpenBelowThreshold(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient,
+		circuitbreaker.WithServiceName(serviceName),
+		circuitbreaker.WithFailureRateThreshold(50),
+		circuitbreaker.WithOpenWindow(time.Minute),
+		circuitbreaker.WithHalfOpenWindow(time.Minute),
+		circuitbreaker.WithPermittedCallsInHalfOpen(2),
+	)
+
+	// one failure below threshold, and circuit is in half open tail of its window.
+	assert.Nil(t, rs.Failure(context.Background(), 1))
+
+	cState, err := rs.GetState(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, cState)
+
+	for i := 0; i < 10; i++ {
+		acquired, err := rs.AcquireHalfOpen(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, acquired, "trial calls are limited while circuit is not tripped")
+	}
+}
+
+func TestRedisStorage_Slow(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Run("slow call rate threshold is not set, expect slow calls to be stored as failures", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, options...)
+
+		assert.Nil(t, rs.Slow(context.Background(), 2))
+		assert.Equal(t, "2", server.HGet(tempkey, failuresField))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
 	})
 
+	t.Run("slow call rate threshold is reached, expect circuit to open", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(options, circuitbreaker.WithSlowCallRateThreshold(3))...)
+
+		assert.Nil(t, rs.Slow(context.Background(), 2))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		assert.Nil(t, rs.Slow(context.Background(), 1))
+		assert.Equal(t, "", server.HGet(tempkey, failuresField))
+
+		state, err = rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+	})
+
+	t.Run("slow call rate of sliding window is reached, expect circuit to open", func(t *testing.T) {
+		server.FlushAll()
+		rs := circuitbreaker.NewRedisStorage(redisClient, append(options,
+			circuitbreaker.WithFailureRateThreshold(50),
+			circuitbreaker.WithSlowCallRateThreshold(50),
+			circuitbreaker.WithCountBasedSlidingWindow(4),
+			circuitbreaker.WithMinimumNumberOfCalls(4),
+		)...)
+
+		assert.Nil(t, rs.Success(context.Background(), 2))
+		assert.Nil(t, rs.Slow(context.Background(), 1))
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		state, err := rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, state)
+
+		assert.Nil(t, rs.Slow(context.Background(), 1))
+
+		state, err = rs.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, state)
+	})
+}
+
+func TestRedisStorage_OpenRemaining(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient, options...)
+
+	remaining, err := rs.OpenRemaining(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), remaining)
+
+	assert.Nil(t, rs.Failure(context.Background(), failureRateThreshold))
+
+	remaining, err = rs.OpenRemaining(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.DefaultOpenWindow-circuitbreaker.DefaultHalfOpenWindow, remaining)
+}
+
+func TestRedisStorage_SameAsMemoryStorage(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	testCases := []struct {
+		Name    string
+		Options []circuitbreaker.StorageOption
+	}{
+		{Name: "failure count", Options: options},
+		{Name: "slow call count", Options: append(options[:len(options):len(options)], circuitbreaker.WithSlowCallRateThreshold(2))},
+		{Name: "count based window", Options: append(options[:len(options):len(options)],
+			circuitbreaker.WithFailureRateThreshold(50),
+			circuitbreaker.WithSlowCallRateThreshold(75),
+			circuitbreaker.WithMinimumNumberOfCalls(4),
+			circuitbreaker.WithCountBasedSlidingWindow(4),
+		)},
+		{Name: "time based window", Options: append(options[:len(options):len(options)],
+			circuitbreaker.WithFailureRateThreshold(50),
+			circuitbreaker.WithMinimumNumberOfCalls(4),
+			circuitbreaker.WithTimeBasedSlidingWindow(time.Minute),
+		)},
+	}
+
+	calls := "ssfdsffsdddssfsffss"
+
+	for _, item := range testCases {
+		item := item
+		t.Run(item.Name, func(t *testing.T) {
+			server.FlushAll()
+
+			storages := []interface {
+				circuitbreaker.Storage
+				circuitbreaker.SlowCallStorage
+			}{
+				circuitbreaker.NewMemoryStorage(item.Options...),
+				circuitbreaker.NewRedisStorage(redisClient, item.Options...),
+			}
+
+			for index, call := range calls {
+				var states []circuitbreaker.State
+
+				for _, storage := range storages {
+					switch call {
+					case 's':
+						assert.Nil(t, storage.Success(context.Background(), 1))
+					case 'f':
+						assert.Nil(t, storage.Failure(context.Background(), 1))
+					case 'd':
+						assert.Nil(t, storage.Slow(context.Background(), 1))
+					}
+
+					state, err := storage.GetState(context.Background())
+					assert.Nil(t, err)
+
+					states = append(states, state)
+				}
+
+				assert.Equal(t, states[0], states[1], "call %d", index)
+			}
+		})
+	}
+}
+
+func TestRedisStorage_UniversalClient(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	clients := map[string]redis.UniversalClient{
+		"cluster":   redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{server.Addr()}}),
+		"universal": redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{server.Addr()}}),
+	}
+
+	for name, client := range clients {
+		client := client
+		t.Run(name, func(t *testing.T) {
+			server.FlushAll()
+			rs := circuitbreaker.NewRedisStorage(client, append(options[:len(options):len(options)],
+				circuitbreaker.WithFailureRateThreshold(50),
+				circuitbreaker.WithMinimumNumberOfCalls(2),
+				circuitbreaker.WithCountBasedSlidingWindow(2),
+			)...)
+
+			assert.Nil(t, rs.Success(context.Background(), 1))
+			assert.True(t, server.Exists(tempkey+":window"))
+
+			assert.Nil(t, rs.Failure(context.Background(), 1))
+			assert.True(t, server.Exists(tempkey))
+
+			state, err := rs.GetState(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, circuitbreaker.StateOpen, state)
+
+			assert.Nil(t, rs.Reset(context.Background()))
+			assert.False(t, server.Exists(tempkey))
+		})
+	}
+}
+
+func TestRedisStorage_NotifyState(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetryBackoff: 10 * time.Millisecond})
+
+	publishOptions := append(options[:len(options):len(options)], circuitbreaker.WithPublishState())
+
+	local := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	defer local.Close()
+
+	another := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	defer another.Close()
+
+	states := make(chan circuitbreaker.State, 10)
+	stop := local.NotifyState(func(state circuitbreaker.State) { states <- state })
+
+	t.Run("expect current state to be notified when subscribed", func(t *testing.T) {
+		assert.Equal(t, circuitbreaker.StateClose, receiveState(t, states))
+	})
+
+	t.Run("another instance opened the circuit, expect to be notified", func(t *testing.T) {
+		assert.Nil(t, another.Failure(context.Background(), failureRateThreshold-1))
+		assert.Nil(t, another.Failure(context.Background(), 1))
+		assert.Equal(t, circuitbreaker.StateOpen, receiveState(t, states))
+	})
+
+	t.Run("another instance closed the circuit, expect to be notified", func(t *testing.T) {
+		assert.Nil(t, another.Success(context.Background(), successRateThreshold))
+		assert.Equal(t, circuitbreaker.StateClose, receiveState(t, states))
+	})
+
+	t.Run("subscription dropped and circuit opened meanwhile, expect to be notified when subscribed again", func(t *testing.T) {
+		server.Close()
+		server.HSet(tempkey, failuresField, strconv.FormatInt(failureRateThreshold, 10))
+		server.SetTTL(tempkey, circuitbreaker.DefaultOpenWindow)
+		assert.Nil(t, server.Restart())
+
+		assert.Equal(t, circuitbreaker.StateOpen, receiveState(t, states))
+	})
+
+	t.Run("publishing is not set, expect to not be notified", func(t *testing.T) {
+		stop()
+
+		silent := circuitbreaker.NewRedisStorage(redisClient, options...)
+		silent.NotifyState(func(state circuitbreaker.State) { states <- state })
+
+		assert.Nil(t, silent.Reset(context.Background()))
+		assert.Nil(t, silent.Failure(context.Background(), failureRateThreshold))
+
+		select {
+		case state := <-states:
+			t.Fatalf("unexpected state notification %d", state)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestRedisStorage_NotifyCircuit(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	publishOptions := append(options[:len(options):len(options)], circuitbreaker.WithPublishState())
+	changes := make(chan circuitbreaker.StateChange, 10)
+
+	storage := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	defer storage.Close()
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		circuitbreaker.WithOnStateChange(func(from, to circuitbreaker.State, stat circuitbreaker.Stat) {
+			changes <- circuitbreaker.StateChange{From: from, To: to, Stat: stat}
+		}),
+	)
+	assert.True(t, breaker.IsAvailable(context.Background()))
+
+	another := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	assert.Nil(t, another.Failure(context.Background(), failureRateThreshold))
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, circuitbreaker.StateClose, change.From)
+		assert.Equal(t, circuitbreaker.StateOpen, change.To)
+	case <-time.After(time.Second):
+		t.Fatal("state change is not notified")
+	}
+}
+
+func TestRedisStorage_SharedSubscription(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	publishOptions := append(options[:len(options):len(options)], circuitbreaker.WithPublishState())
+	otherOptions := append(publishOptions[:len(publishOptions):len(publishOptions)], circuitbreaker.WithServiceName("other"))
+
+	local := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	another := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	other := circuitbreaker.NewRedisStorage(redisClient, otherOptions...)
+
+	localStates, anotherStates, otherStates := make(chan circuitbreaker.State, 10), make(chan circuitbreaker.State, 10), make(chan circuitbreaker.State, 10)
+	local.NotifyState(func(state circuitbreaker.State) { localStates <- state })
+	another.NotifyState(func(state circuitbreaker.State) { anotherStates <- state })
+	other.NotifyState(func(state circuitbreaker.State) { otherStates <- state })
+
+	t.Run("storages of a client are subscribed, expect one subscription and current state to be notified", func(t *testing.T) {
+		assert.Equal(t, circuitbreaker.StateClose, receiveState(t, localStates))
+		assert.Equal(t, circuitbreaker.StateClose, receiveState(t, anotherStates))
+		assert.Equal(t, circuitbreaker.StateClose, receiveState(t, otherStates))
+		assert.Equal(t, 1, server.PubSubNumPat())
+	})
+
+	t.Run("circuit of a service is opened, expect only storages of that service to be notified", func(t *testing.T) {
+		assert.Nil(t, other.Failure(context.Background(), failureRateThreshold))
+		assert.Equal(t, circuitbreaker.StateOpen, receiveState(t, otherStates))
+
+		select {
+		case state := <-localStates:
+			t.Fatalf("unexpected state notification %d", state)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("some storages are closed, expect others to be notified", func(t *testing.T) {
+		assert.Nil(t, other.Close())
+		assert.Nil(t, local.Close())
+
+		assert.Nil(t, another.Failure(context.Background(), failureRateThreshold))
+		assert.Equal(t, circuitbreaker.StateOpen, receiveState(t, anotherStates))
+		assert.Equal(t, 1, server.PubSubNumPat())
+	})
+
+	t.Run("all storages are closed, expect subscription to be closed", func(t *testing.T) {
+		assert.Nil(t, another.Close())
+		assert.Eventually(t, func() bool { return server.PubSubNumPat() == 0 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func receiveState(t *testing.T, states <-chan circuitbreaker.State) circuitbreaker.State {
+	t.Helper()
+
+	select {
+	case state := <-states:
+		return state
+	case <-time.After(5 * time.Second):
+		t.Fatal("state is not notified")
+	}
+
+	return circuitbreaker.StateUnknown
+}
+
+func TestRedisStorage_Backoff(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient,
+		circuitbreaker.WithServiceName(serviceName),
+		circuitbreaker.WithOpenWindow(time.Minute),
+		circuitbreaker.WithHalfOpenWindow(10*time.Second),
+		circuitbreaker.WithFailureRateThreshold(1),
+		circuitbreaker.WithSuccessRateThreshold(1),
+		circuitbreaker.WithOpenWindowBackoff(2, 3*time.Minute),
+	)
+
+	// moveToHalfOpen moves time forward, so circuit is in half open window.
+	moveToHalfOpen := func() {
+		server.FastForward(server.TTL(tempkey) - 5*time.Second)
+	}
+
+	assert.Nil(t, rs.Failure(context.Background(), 1))
+	assert.Equal(t, time.Minute, server.TTL(tempkey))
+
+	t.Run("expect each failure in half open state to multiply the open window up to max", func(t *testing.T) {
+		for _, expected := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+			moveToHalfOpen()
+
+			state, err := rs.GetState(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, circuitbreaker.StateHalfOpen, state)
+
+			assert.Nil(t, rs.Failure(context.Background(), 1))
+			assert.Equal(t, expected, server.TTL(tempkey))
+		}
+
+		assert.Equal(t, "3", server.HGet(tempkey, "trips"))
+	})
+
+	t.Run("circuit is closed, expect open window to be back to default", func(t *testing.T) {
+		moveToHalfOpen()
+		assert.Nil(t, rs.Success(context.Background(), 1))
+		assert.Nil(t, rs.Failure(context.Background(), 1))
+
+		assert.Equal(t, "", server.HGet(tempkey, "trips"))
+		assert.Equal(t, time.Minute, server.TTL(tempkey))
+	})
+}
+
+func TestRedisStorage_Override(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	rs := circuitbreaker.NewRedisStorage(redisClient, options...)
+	other := circuitbreaker.NewRedisStorage(redisClient, options...)
+
+	t.Run("force open on one instance, expect all instances to be open", func(t *testing.T) {
+		assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideForceOpen, time.Minute))
+
+		override, err := other.GetOverride(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.OverrideForceOpen, override)
+
+		cState, err := other.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, cState)
+
+		remaining, err := other.OpenRemaining(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, time.Minute, remaining)
+	})
+
+	t.Run("ttl passed, expect override to expire", func(t *testing.T) {
+		server.FastForward(time.Minute)
+
+		override, err := other.GetOverride(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.OverrideNone, override)
+
+		cState, err := other.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, cState)
+	})
+
+	t.Run("force close, expect close state and failures to be stored", func(t *testing.T) {
+		assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideForceClose, 0))
+		assert.Nil(t, other.Failure(context.Background(), failureRateThreshold))
+
+		cState, err := other.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, cState)
+		assert.Equal(t, strconv.FormatInt(failureRateThreshold, 10), server.HGet(tempkey, failuresField))
+
+		assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideNone, 0))
+
+		cState, err = other.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateOpen, cState)
+		assert.Nil(t, rs.Reset(context.Background()))
+	})
+
+	t.Run("disable, expect failures to not be stored", func(t *testing.T) {
+		assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideDisable, 0))
+		assert.Nil(t, other.Failure(context.Background(), failureRateThreshold))
+		assert.False(t, server.Exists(tempkey))
+
+		assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideNone, 0))
+
+		cState, err := other.GetState(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, circuitbreaker.StateClose, cState)
+	})
+}
+
+func TestRedisStorage_NotifyOverride(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	publishOptions := append(options[:len(options):len(options)], circuitbreaker.WithPublishState())
+	rs := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+	other := circuitbreaker.NewRedisStorage(redisClient, publishOptions...)
+
+	defer other.Close()
+
+	states := make(chan circuitbreaker.State, 10)
+	other.NotifyState(func(state circuitbreaker.State) { states <- state })
+
+	// state is read once subscribed.
+	assert.Equal(t, circuitbreaker.StateClose, receiveState(t, states))
+
+	assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideForceOpen, 0))
+	assert.Equal(t, circuitbreaker.StateOpen, receiveState(t, states))
+
+	assert.Nil(t, rs.SetOverride(context.Background(), circuitbreaker.OverrideNone, 0))
+	assert.Equal(t, circuitbreaker.StateClose, receiveState(t, states))
+}
+
+func TestServiceOfKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		circuitbreaker.StorageKey("users"):             "users",
+		circuitbreaker.StorageKey("users") + ":window": "users",
+		circuitbreaker.StorageKey("a:b") + ":override": "a:b",
+	} {
+		service, ok := circuitbreaker.ServiceOfKey(key)
+		assert.True(t, ok)
+		assert.Equal(t, expected, service)
+	}
+
+	_, ok := circuitbreaker.ServiceOfKey("other:{users}")
+	assert.False(t, ok)
 }
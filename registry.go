@@ -0,0 +1,308 @@
+package circuitbreaker
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// StorageFactory create the storage of a circuit in Registry.
+type StorageFactory func(options ...StorageOption) Storage
+
+// MemoryStorageFactory create a MemoryStorage for each circuit.
+func MemoryStorageFactory() StorageFactory {
+	return func(options ...StorageOption) Storage {
+		return NewMemoryStorage(options...)
+	}
+}
+
+// RedisStorageFactory create a RedisStorage for each circuit, all sharing the client.
+func RedisStorageFactory(client redis.UniversalClient) StorageFactory {
+	return func(options ...StorageOption) Storage {
+		return NewRedisStorage(client, options...)
+	}
+}
+
+// RegistryOptions is Registry options.
+type RegistryOptions struct {
+	// StorageFactory create storage of each circuit, default is MemoryStorageFactory
+	StorageFactory StorageFactory
+	// Options is the template options of all circuits
+	Options []Option
+	// StorageOptions is the template storage options of all circuits
+	StorageOptions []StorageOption
+	// KeyOptions are options of a key that are applied after the template
+	KeyOptions map[string][]Option
+	// KeyStorageOptions are storage options of a key that are applied after the template
+	KeyStorageOptions map[string][]StorageOption
+	// IdleTimeout is the duration that circuits not used in it are evicted
+	// if its 0, then circuits are never evicted
+	IdleTimeout time.Duration
+}
+
+// RegistryOption configures the Registry.
+type RegistryOption func(*RegistryOptions)
+
+// WithStorageFactory sets the factory that create storage of each circuit.
+func WithStorageFactory(factory StorageFactory) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.StorageFactory = factory
+	}
+}
+
+// WithCircuitOptions sets the template options of all circuits, storage of circuits is created by StorageFactory.
+func WithCircuitOptions(options ...Option) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Options = append(o.Options, options...)
+	}
+}
+
+// WithStorageOptions sets the template storage options of all circuits, service name is set to the circuit key.
+func WithStorageOptions(options ...StorageOption) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.StorageOptions = append(o.StorageOptions, options...)
+	}
+}
+
+// WithKeyOptions overrides the template options for circuit of key.
+func WithKeyOptions(key string, options ...Option) RegistryOption {
+	return func(o *RegistryOptions) {
+		if o.KeyOptions == nil {
+			o.KeyOptions = make(map[string][]Option)
+		}
+
+		o.KeyOptions[key] = append(o.KeyOptions[key], options...)
+	}
+}
+
+// WithKeyStorageOptions overrides the template storage options for circuit of key.
+func WithKeyStorageOptions(key string, options ...StorageOption) RegistryOption {
+	return func(o *RegistryOptions) {
+		if o.KeyStorageOptions == nil {
+			o.KeyStorageOptions = make(map[string][]StorageOption)
+		}
+
+		o.KeyStorageOptions[key] = append(o.KeyStorageOptions[key], options...)
+	}
+}
+
+// WithIdleTimeout sets the duration that circuits not used in it are evicted.
+func WithIdleTimeout(timeout time.Duration) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.IdleTimeout = timeout
+	}
+}
+
+type registryEntry struct {
+	circuit *Circuit
+	// lastUsed is unix nano time of last Get.
+	lastUsed int64
+}
+
+// close the storage of circuit if it needs to be closed, like CachedStorage.
+func (e *registryEntry) close() {
+	if closer, ok := e.circuit.ops.Storage.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// Registry lazily create and cache a Circuit per key, it is concurrent safe.
+// its Manager method can be used as the circuit selector of http and grpc packages.
+type Registry struct {
+	ops      RegistryOptions
+	lock     sync.RWMutex
+	circuits map[string]*registryEntry
+	now      func() time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRegistry create new instance of Registry.
+// if IdleTimeout is set, idle circuits are evicted in background until Close is called.
+func NewRegistry(options ...RegistryOption) *Registry {
+	registry := Registry{
+		ops:      RegistryOptions{StorageFactory: MemoryStorageFactory()},
+		circuits: make(map[string]*registryEntry),
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+
+	for _, op := range options {
+		op(&registry.ops)
+	}
+
+	if registry.ops.IdleTimeout > 0 {
+		go registry.evictLoop()
+	}
+
+	return &registry
+}
+
+// Get the circuit of key, it is created if not exist.
+func (r *Registry) Get(key string) *Circuit {
+	now := r.now().UnixNano()
+
+	r.lock.RLock()
+	entry, ok := r.circuits[key]
+	r.lock.RUnlock()
+
+	if ok {
+		atomic.StoreInt64(&entry.lastUsed, now)
+
+		return entry.circuit
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if entry, ok = r.circuits[key]; !ok {
+		entry = &registryEntry{circuit: r.newCircuit(key)}
+		r.circuits[key] = entry
+	}
+
+	atomic.StoreInt64(&entry.lastUsed, now)
+
+	return entry.circuit
+}
+
+// Lookup the circuit of key, it is not created if not exist and its idle time is not changed.
+func (r *Registry) Lookup(key string) (*Circuit, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	entry, ok := r.circuits[key]
+	if !ok {
+		return nil, false
+	}
+
+	return entry.circuit, true
+}
+
+// Manager is Get that returns a Manager.
+func (r *Registry) Manager(key string) Manager {
+	return r.Get(key)
+}
+
+func (r *Registry) newCircuit(key string) *Circuit {
+	storageOptions := append([]StorageOption{StorageWithDefaultOptions()}, r.ops.StorageOptions...)
+	storageOptions = append(storageOptions, WithServiceName(key))
+	storageOptions = append(storageOptions, r.ops.KeyStorageOptions[key]...)
+
+	options := append([]Option{func(o *Options) {
+		o.State = DefaultState
+		o.Logger = NewIOLogger(os.Stdout, OutPutTypeSimple)
+	}}, r.ops.Options...)
+	options = append(options, r.ops.KeyOptions[key]...)
+	options = append(options, WithStorage(r.ops.StorageFactory(storageOptions...)))
+
+	return NewCircuit(options...)
+}
+
+// Keys of all circuits, sorted.
+func (r *Registry) Keys() []string {
+	r.lock.RLock()
+	keys := make([]string, 0, len(r.circuits))
+
+	for key := range r.circuits {
+		keys = append(keys, key)
+	}
+	r.lock.RUnlock()
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Stats of all circuits by their key.
+func (r *Registry) Stats(ctx context.Context) map[string]Stat {
+	r.lock.RLock()
+	circuits := make(map[string]*Circuit, len(r.circuits))
+
+	for key, entry := range r.circuits {
+		circuits[key] = entry.circuit
+	}
+	r.lock.RUnlock()
+
+	stats := make(map[string]Stat, len(circuits))
+
+	for key, circuit := range circuits {
+		stats[key] = circuit.Stat(ctx)
+	}
+
+	return stats
+}
+
+// Remove the circuit of key, next Get creates a new circuit.
+func (r *Registry) Remove(key string) {
+	r.lock.Lock()
+	entry, ok := r.circuits[key]
+	delete(r.circuits, key)
+	r.lock.Unlock()
+
+	// storage may write to network when it is closed, so it is not done while holding the lock.
+	if ok {
+		entry.close()
+	}
+}
+
+// Evict circuits that are not used in IdleTimeout, and returns number of evicted circuits.
+func (r *Registry) Evict() int {
+	if r.ops.IdleTimeout <= 0 {
+		return 0
+	}
+
+	deadline := r.now().Add(-r.ops.IdleTimeout).UnixNano()
+
+	var evicted []*registryEntry
+
+	r.lock.Lock()
+	for key, entry := range r.circuits {
+		if atomic.LoadInt64(&entry.lastUsed) < deadline {
+			delete(r.circuits, key)
+			evicted = append(evicted, entry)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, entry := range evicted {
+		entry.close()
+	}
+
+	return len(evicted)
+}
+
+func (r *Registry) evictLoop() {
+	ticker := time.NewTicker(r.ops.IdleTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Evict()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close stops background eviction of idle circuits, and closes storage of all circuits.
+func (r *Registry) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	r.lock.Lock()
+	entries := r.circuits
+	r.circuits = make(map[string]*registryEntry)
+	r.lock.Unlock()
+
+	for _, entry := range entries {
+		entry.close()
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Get(t *testing.T) {
+	registry := NewRegistry(
+		WithStorageOptions(WithFailureRateThreshold(2), WithOpenWindow(time.Minute)),
+		WithKeyStorageOptions("fragile", WithFailureRateThreshold(1)),
+		WithKeyOptions("fragile", WithFallbackState(StateOpen)),
+	)
+
+	t.Run("expect same circuit for same key and service name to be the key", func(t *testing.T) {
+		circuit := registry.Get("users")
+		assert.Same(t, circuit, registry.Get("users"))
+		assert.Equal(t, registry.Manager("users"), Manager(circuit))
+		assert.NotSame(t, circuit, registry.Get("orders"))
+
+		storage := circuit.ops.Storage.(*MemoryStorage)
+		assert.Equal(t, "users", storage.options.Service)
+		assert.Equal(t, int64(2), storage.options.FailureRateThreshold)
+		assert.Equal(t, DefaultHalfOpenWindow, storage.options.HalfOpenWindow)
+		assert.Equal(t, DefaultState, circuit.ops.State)
+	})
+
+	t.Run("key has overrides, expect them to be applied after the template", func(t *testing.T) {
+		circuit := registry.Get("fragile")
+
+		storage := circuit.ops.Storage.(*MemoryStorage)
+		assert.Equal(t, int64(1), storage.options.FailureRateThreshold)
+		assert.Equal(t, time.Minute, storage.options.OpenWindow)
+		assert.Equal(t, StateOpen, circuit.ops.State)
+	})
+
+	t.Run("expect to enumerate all circuits with their stat", func(t *testing.T) {
+		registry.Get("fragile").Done(context.Background(), errors.New("some error"))
+
+		assert.Equal(t, []string{"fragile", "orders", "users"}, registry.Keys())
+		assert.Equal(t, map[string]Stat{
+			"fragile": {State: StateOpen, Failure: 1},
+			"orders":  {State: StateClose},
+			"users":   {State: StateClose},
+		}, registry.Stats(context.Background()))
+	})
+
+	t.Run("circuit is removed, expect new circuit on next get", func(t *testing.T) {
+		circuit := registry.Get("users")
+		registry.Remove("users")
+
+		assert.NotSame(t, circuit, registry.Get("users"))
+	})
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	registry := NewRegistry()
+
+	_, ok := registry.Lookup("users")
+	assert.False(t, ok)
+	assert.Empty(t, registry.Keys())
+
+	circuit := registry.Get("users")
+
+	found, ok := registry.Lookup("users")
+	assert.True(t, ok)
+	assert.Same(t, circuit, found)
+}
+
+func TestRegistry_Evict(t *testing.T) {
+	t.Run("idle timeout is not set, expect to not evict", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Get("users")
+
+		assert.Equal(t, 0, registry.Evict())
+		assert.Equal(t, []string{"users"}, registry.Keys())
+	})
+
+	t.Run("expect to only evict circuits that are not used in idle timeout", func(t *testing.T) {
+		registry := NewRegistry(WithIdleTimeout(time.Minute))
+		defer registry.Close()
+
+		now := time.Now()
+		registry.now = func() time.Time { return now }
+
+		registry.Get("users")
+		registry.Get("orders")
+
+		now = now.Add(40 * time.Second)
+		registry.Get("users")
+
+		now = now.Add(40 * time.Second)
+		assert.Equal(t, 1, registry.Evict())
+		assert.Equal(t, []string{"users"}, registry.Keys())
+	})
+}
+
+func TestRegistry_Close(t *testing.T) {
+	var storages []*CachedStorage
+
+	registry := NewRegistry(WithStorageFactory(func(options ...StorageOption) Storage {
+		storage := NewCachedStorage(NewMemoryStorage(options...), WithFlushInterval(time.Hour))
+		storages = append(storages, storage)
+
+		return storage
+	}))
+
+	registry.Get("users")
+	registry.Get("orders")
+
+	registry.Remove("users")
+	assert.Nil(t, registry.Close())
+	assert.Empty(t, registry.Keys())
+
+	for _, storage := range storages {
+		select {
+		case <-storage.stopped:
+		default:
+			t.Fatal("storage is not closed")
+		}
+	}
+}
+
+func TestRegistry_CloseWithoutLock(t *testing.T) {
+	closing, release := make(chan struct{}), make(chan struct{})
+
+	registry := NewRegistry(WithStorageFactory(func(options ...StorageOption) Storage {
+		return &blockingStorage{MemoryStorage: NewMemoryStorage(options...), closing: closing, release: release}
+	}))
+
+	registry.Get("users")
+
+	go registry.Remove("users")
+	<-closing
+
+	// storage of users is still closing, expect other circuits to not wait for it.
+	got := make(chan struct{})
+
+	go func() {
+		registry.Get("orders")
+		close(got)
+	}()
+
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("get is blocked by closing storage")
+	}
+
+	close(release)
+}
+
+// blockingStorage blocks on Close until release is closed.
+type blockingStorage struct {
+	*MemoryStorage
+	closing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) Close() error {
+	close(s.closing)
+	<-s.release
+
+	return nil
+}
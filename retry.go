@@ -0,0 +1,88 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryableFunc reports if a call that returned err should be tried again.
+type RetryableFunc func(err error) bool
+
+type withoutRetryKey struct{}
+
+// ContextWithoutRetry returns a ctx that makes Do and DoContext to call fn once even if retry is set,
+// like for a request that can not be sent again. calls made with the returned ctx are not retried either.
+func ContextWithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutRetryKey{}, true)
+}
+
+func retryDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(withoutRetryKey{}).(bool)
+
+	return disabled
+}
+
+// retry call fn through the circuit until it succeeds, RetryAttempts is reached or err is not retryable.
+// circuit is checked before every attempt, and each attempt is stored on its own.
+// if ctx is done or its deadline is before the next attempt, the error of last attempt is returned.
+func (s *Circuit) retry(ctx context.Context, fn ContextFn) (res interface{}, err error) {
+	for attempt := int64(1); ; attempt++ {
+		res, err = s.attempt(ctx, fn)
+		if err == nil || attempt >= s.ops.RetryAttempts || !s.retryable(ctx, err) {
+			return res, err
+		}
+
+		if !wait(ctx, retryBackoff(attempt, s.ops)) {
+			return res, err
+		}
+	}
+}
+
+// retryable reports if err of an attempt is worth another one, open circuit and done ctx are never retried.
+func (s *Circuit) retryable(ctx context.Context, err error) bool {
+	if errors.Is(err, ErrIsOpen) || ctx.Err() != nil {
+		return false
+	}
+
+	if s.ops.Retryable != nil {
+		return s.ops.Retryable(err)
+	}
+
+	return s.classify(err) == OutcomeFailure
+}
+
+// retryBackoff is the wait after attempt, it is doubled for each attempt up to MaxRetryBackoff.
+func retryBackoff(attempt int64, options Options) time.Duration {
+	backoff := float64(options.RetryBackoff) * math.Pow(2, float64(attempt-1))
+
+	if options.MaxRetryBackoff > 0 && backoff > float64(options.MaxRetryBackoff) {
+		backoff = float64(options.MaxRetryBackoff)
+	}
+
+	if options.RetryJitter > 0 {
+		// nolint:gosec
+		backoff -= backoff * rand.Float64() * math.Min(options.RetryJitter, 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// wait for duration, it reports false without waiting if ctx deadline is before it, or if ctx is done while waiting.
+func wait(ctx context.Context, duration time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < duration {
+		return false
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_Retry(t *testing.T) {
+	storage := []circuitbreaker.StorageOption{
+		circuitbreaker.WithFailureRateThreshold(3),
+		circuitbreaker.WithSuccessRateThreshold(1),
+		circuitbreaker.WithOpenWindow(time.Minute),
+	}
+
+	someErr := errors.New("some error")
+
+	// failing returns a fn that fails n times and then succeeds, and the number of its calls.
+	failing := func(n int) (circuitbreaker.Fn, *int) {
+		calls := 0
+
+		return func() (interface{}, error) {
+			calls++
+			if calls <= n {
+				return nil, someErr
+			}
+
+			return "response", nil
+		}, &calls
+	}
+
+	t.Run("call fails and then succeeds, expect each attempt to be stored", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithRetry(3, time.Millisecond, 0))
+		fn, calls := failing(2)
+
+		res, err := breaker.Do(context.Background(), fn)
+		assert.Nil(t, err)
+		assert.Equal(t, "response", res)
+		assert.Equal(t, 3, *calls)
+
+		stat := breaker.Stat(context.Background())
+		assert.Equal(t, int64(2), stat.Failure)
+		assert.Equal(t, int64(1), stat.Success)
+	})
+
+	t.Run("attempts are reached, expect error of last attempt", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithRetry(2, time.Millisecond, 0))
+		fn, calls := failing(5)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, someErr, err)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("circuit opens, expect retries to stop", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithRetry(5, time.Millisecond, 0))
+		fn, calls := failing(5)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, circuitbreaker.ErrIsOpen, err)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("error is not retryable, expect one attempt", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage,
+			circuitbreaker.WithRetry(3, time.Millisecond, 0),
+			circuitbreaker.WithRetryable(func(err error) bool { return !errors.Is(err, someErr) }),
+		)
+		fn, calls := failing(1)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, someErr, err)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("ignored errors, expect them to not be retried by default", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage,
+			circuitbreaker.WithRetry(3, time.Millisecond, 0),
+			circuitbreaker.WithErrorClassifier(circuitbreaker.IgnoreTheseErrorsClassifier(someErr)),
+		)
+		fn, calls := failing(1)
+
+		_, err := breaker.Do(context.Background(), fn)
+		assert.Equal(t, someErr, err)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("ctx deadline is before next attempt, expect retries to stop without waiting", func(t *testing.T) {
+		breaker := newMemoryCircuit(storage, circuitbreaker.WithRetry(3, time.Minute, 0))
+		fn, calls := failing(5)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		start := time.Now()
+
+		_, err := breaker.Do(ctx, fn)
+		assert.Equal(t, someErr, err)
+		assert.Equal(t, 1, *calls)
+		assert.True(t, time.Since(start) < time.Second)
+	})
+}
@@ -2,6 +2,9 @@ package circuitbreaker
 
 import (
 	"context"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Storage is what circut breaker use to store it state.
@@ -12,6 +15,54 @@ type Storage interface {
 	Reset(ctx context.Context) error
 }
 
+// WindowedStorage is a Storage that evaluates failure rate over a sliding window,
+// so it needs to be notified of successes while the circuit is close.
+type WindowedStorage interface {
+	Windowed() bool
+}
+
+// HalfOpenLimiter is a Storage that limits number of trial calls in half open state.
+type HalfOpenLimiter interface {
+	// AcquireHalfOpen reports if one more trial call is permitted in current half open window.
+	AcquireHalfOpen(ctx context.Context) (bool, error)
+}
+
+// SlowCallStorage is a Storage that keeps slow calls apart from failures.
+type SlowCallStorage interface {
+	Slow(ctx context.Context, delta int64) error
+}
+
+// OpenWindowStorage is a Storage that knows when an open circuit moves to half open state.
+type OpenWindowStorage interface {
+	// OpenRemaining is the time left until the circuit moves to half open state, it is zero if circuit is not open.
+	OpenRemaining(ctx context.Context) (time.Duration, error)
+}
+
+// DegradedStorage is a Storage that can fall back to a less accurate storage, like a local one when the distributed one fails.
+type DegradedStorage interface {
+	// Degraded reports if storage is in fall back mode.
+	Degraded() bool
+}
+
+// StateNotifier is a Storage that notifies state changes made by any instance sharing it.
+type StateNotifier interface {
+	// NotifyState calls fn with the new state on each change, call the returned function to stop it.
+	NotifyState(fn func(state State)) func()
+}
+
+// OverrideStorage is a Storage that keeps a manual override of the state, shared by all instances using it.
+type OverrideStorage interface {
+	// SetOverride applies override until ttl is passed, if ttl is 0 it does not expire. OverrideNone clears it.
+	SetOverride(ctx context.Context, override Override, ttl time.Duration) error
+	// GetOverride currently applied.
+	GetOverride(ctx context.Context) (Override, error)
+}
+
+// OptionsStorage is a Storage that exposes its options, like thresholds and windows.
+type OptionsStorage interface {
+	StorageOptions() StorageOptions
+}
+
 // nolint
 const (
 	RedisStorageName  = "redis"
@@ -22,6 +73,65 @@ const (
 	storagePrefix = "circuitBreaker:"
 )
 
+// namespace of service keys, service is wrapped in a hash tag so all keys of a service are in the same redis cluster slot.
 func namespace(service string) string {
-	return storagePrefix + service
+	return storagePrefix + "{" + service + "}"
+}
+
+// StorageKeyPattern is the glob pattern of all keys of distributed storages, like RedisStorage.
+const StorageKeyPattern = storagePrefix + "{*}*"
+
+// StorageKey is the key of service in distributed storages, other keys of service have it as prefix.
+func StorageKey(service string) string {
+	return namespace(service)
+}
+
+// ServiceOfKey returns the service of a storage key, it is false if key is not a storage key.
+func ServiceOfKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, storagePrefix+"{") {
+		return "", false
+	}
+
+	end := strings.LastIndex(key, "}")
+	if end < 0 {
+		return "", false
+	}
+
+	return key[len(storagePrefix)+1 : end], true
+}
+
+// stateListeners are the functions that are notified of state changes of a StateNotifier.
+type stateListeners struct {
+	lock   sync.RWMutex
+	lastID int
+	fns    map[int]func(state State)
+}
+
+func (l *stateListeners) add(fn func(state State)) func() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.fns == nil {
+		l.fns = make(map[int]func(state State))
+	}
+
+	l.lastID++
+	id := l.lastID
+	l.fns[id] = fn
+
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		delete(l.fns, id)
+	}
+}
+
+func (l *stateListeners) notify(state State) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	for _, fn := range l.fns {
+		fn(state)
+	}
 }
@@ -0,0 +1,57 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type callResult struct {
+	res   interface{}
+	err   error
+	panic interface{}
+}
+
+// callWithTimeout call fn and wait for it until timeout, fn get a ctx that is canceled after timeout.
+// if fn does not respect its ctx, it keeps running in background but its result is dropped.
+func callWithTimeout(ctx context.Context, timeout time.Duration, fn ContextFn) (interface{}, error) {
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make(chan callResult, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				results <- callResult{panic: p}
+			}
+		}()
+
+		res, err := fn(callCtx)
+		results <- callResult{res: res, err: err}
+	}()
+
+	select {
+	case result := <-results:
+		if result.panic != nil {
+			panic(result.panic)
+		}
+
+		if result.err != nil && timedOut(ctx, callCtx) {
+			return result.res, ErrTimeout
+		}
+
+		return result.res, result.err
+	case <-callCtx.Done():
+		if timedOut(ctx, callCtx) {
+			return nil, ErrTimeout
+		}
+
+		return nil, ctx.Err()
+	}
+}
+
+// timedOut reports if call ctx is done because of its own deadline, not the ctx of caller.
+func timedOut(ctx, callCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded)
+}
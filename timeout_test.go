@@ -0,0 +1,115 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrsoftware/circuitbreaker"
+	"github.com/mrsoftware/circuitbreaker/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_DoContext(t *testing.T) {
+	storage := &mock.Storage{}
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		circuitbreaker.WithCallTimeout(10*time.Millisecond),
+	)
+
+	t.Run("call finish in time, expect to get its result", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Twice()
+
+		response, err := breaker.DoContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+			return "response", nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "response", response)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("call does not finish in time, expect ErrTimeout and ctx of call to be canceled and failure to be stored", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+
+		response, err := breaker.DoContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		})
+		assert.Equal(t, circuitbreaker.ErrTimeout, err)
+		assert.Nil(t, response)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("fn does not respect ctx, expect Do to not wait for it", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+		storage.On("Failure", context.Background(), int64(1)).Return(nil).Once()
+
+		_, err := breaker.Do(context.Background(), func() (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+
+			return "response", nil
+		})
+		assert.Equal(t, circuitbreaker.ErrTimeout, err)
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("ctx of caller is canceled, expect to not store the result", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		storage.On("GetState", ctx).Return(circuitbreaker.StateClose, nil).Once()
+
+		_, err := breaker.DoContext(ctx, func(ctx context.Context) (interface{}, error) {
+			cancel()
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		})
+		assert.True(t, errors.Is(err, context.Canceled))
+
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("fn panics, expect panic to be passed to caller", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Twice()
+
+		assert.PanicsWithValue(t, "some panic", func() {
+			_, _ = breaker.DoContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+				panic("some panic")
+			})
+		})
+
+		storage.AssertExpectations(t)
+	})
+}
+
+func TestCircuitBreaker_IgnoreTimeout(t *testing.T) {
+	storage := &mock.Storage{}
+
+	breaker := circuitbreaker.NewCircuit(
+		circuitbreaker.WithStorage(storage),
+		circuitbreaker.WithFallbackState(circuitbreaker.StateClose),
+		circuitbreaker.WithCallTimeout(time.Millisecond),
+		circuitbreaker.WithIgnoreTimeout(),
+	)
+
+	t.Run("call does not finish in time, expect ErrTimeout and failure to not be stored", func(t *testing.T) {
+		storage.On("GetState", context.Background()).Return(circuitbreaker.StateClose, nil).Once()
+
+		_, err := breaker.DoContext(context.Background(), func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		})
+		assert.Equal(t, circuitbreaker.ErrTimeout, err)
+
+		storage.AssertExpectations(t)
+	})
+}
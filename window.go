@@ -0,0 +1,168 @@
+package circuitbreaker
+
+import (
+	"time"
+)
+
+// windowBuckets is number of buckets that a time based window is divided into.
+const windowBuckets = 10
+
+// window is a sliding window of call outcomes, it is not concurrent safe.
+type window interface {
+	record(failures, slow, success int64)
+	counts() (failures, slow, total int64)
+	reset()
+}
+
+func newWindow(options StorageOptions) window {
+	switch options.SlidingWindowType {
+	case SlidingWindowCountBased:
+		return newCountWindow(options.SlidingWindowSize)
+	case SlidingWindowTimeBased:
+		return newTimeWindow(options.SlidingWindowDuration)
+	}
+
+	return nil
+}
+
+// exceedRate checks if failures or slow calls reached their threshold percentage of total calls.
+func exceedRate(failures, slow, total int64, options StorageOptions) bool {
+	if total == 0 || total < options.MinimumNumberOfCalls {
+		return false
+	}
+
+	if options.SlowCallRateThreshold > 0 && slow*100 >= options.SlowCallRateThreshold*total {
+		return true
+	}
+
+	return failures*100 >= options.FailureRateThreshold*total
+}
+
+type outcome uint8
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeSlow
+)
+
+// countWindow keeps outcome of last size calls.
+type countWindow struct {
+	outcomes []outcome
+	next     int
+	filled   int
+	failures int64
+	slow     int64
+}
+
+func newCountWindow(size int64) *countWindow {
+	return &countWindow{outcomes: make([]outcome, size)}
+}
+
+func (c *countWindow) record(failures, slow, success int64) {
+	c.pushN(outcomeSuccess, success)
+	c.pushN(outcomeSlow, slow)
+	c.pushN(outcomeFailure, failures)
+}
+
+// pushN push item count times, but not more than window size, as the rest would be pushed out by them.
+func (c *countWindow) pushN(item outcome, count int64) {
+	if count > int64(len(c.outcomes)) {
+		count = int64(len(c.outcomes))
+	}
+
+	for ; count > 0; count-- {
+		c.push(item)
+	}
+}
+
+func (c *countWindow) push(item outcome) {
+	if c.filled == len(c.outcomes) {
+		c.count(c.outcomes[c.next], -1)
+	}
+
+	if c.filled < len(c.outcomes) {
+		c.filled++
+	}
+
+	c.count(item, 1)
+	c.outcomes[c.next] = item
+	c.next = (c.next + 1) % len(c.outcomes)
+}
+
+func (c *countWindow) count(item outcome, delta int64) {
+	switch item {
+	case outcomeFailure:
+		c.failures += delta
+	case outcomeSlow:
+		c.slow += delta
+	}
+}
+
+func (c *countWindow) counts() (failures, slow, total int64) {
+	return c.failures, c.slow, int64(c.filled)
+}
+
+func (c *countWindow) reset() {
+	c.next, c.filled, c.failures, c.slow = 0, 0, 0, 0
+}
+
+// timeWindow keeps outcome of calls in last duration, divided into windowBuckets buckets.
+type timeWindow struct {
+	width   time.Duration
+	buckets [windowBuckets]bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	index    int64
+	failures int64
+	slow     int64
+	success  int64
+}
+
+func newTimeWindow(duration time.Duration) *timeWindow {
+	width := duration / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+
+	return &timeWindow{width: width, now: time.Now}
+}
+
+func (t *timeWindow) current() int64 {
+	return t.now().UnixNano() / int64(t.width)
+}
+
+func (t *timeWindow) record(failures, slow, success int64) {
+	index := t.current()
+	b := &t.buckets[index%windowBuckets]
+
+	if b.index != index {
+		*b = bucket{index: index}
+	}
+
+	b.failures += failures
+	b.slow += slow
+	b.success += success
+}
+
+func (t *timeWindow) counts() (failures, slow, total int64) {
+	index := t.current()
+
+	for _, b := range t.buckets {
+		if index-b.index >= windowBuckets {
+			continue
+		}
+
+		failures += b.failures
+		slow += b.slow
+		total += b.failures + b.slow + b.success
+	}
+
+	return failures, slow, total
+}
+
+func (t *timeWindow) reset() {
+	t.buckets = [windowBuckets]bucket{}
+}
@@ -0,0 +1,80 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountWindow(t *testing.T) {
+	t.Run("expect to only keep outcome of last calls", func(t *testing.T) {
+		w := newCountWindow(3)
+
+		w.record(2, 0, 0)
+		w.record(0, 1, 1)
+
+		failures, slow, total := w.counts()
+		assert.Equal(t, int64(1), failures)
+		assert.Equal(t, int64(1), slow)
+		assert.Equal(t, int64(3), total)
+	})
+
+	t.Run("delta is bigger than window, expect window to be filled with last outcomes", func(t *testing.T) {
+		w := newCountWindow(3)
+
+		w.record(1<<40, 0, 1<<40)
+
+		failures, slow, total := w.counts()
+		assert.Equal(t, int64(3), failures)
+		assert.Equal(t, int64(0), slow)
+		assert.Equal(t, int64(3), total)
+	})
+
+	t.Run("expect reset to clear the window", func(t *testing.T) {
+		w := newCountWindow(3)
+
+		w.record(2, 1, 1)
+		w.reset()
+
+		failures, slow, total := w.counts()
+		assert.Equal(t, int64(0), failures)
+		assert.Equal(t, int64(0), slow)
+		assert.Equal(t, int64(0), total)
+	})
+}
+
+func TestTimeWindow(t *testing.T) {
+	t.Run("expect to only keep outcome of calls in the window duration", func(t *testing.T) {
+		now := time.Now()
+		w := newTimeWindow(10 * time.Second)
+		w.now = func() time.Time { return now }
+
+		w.record(3, 1, 1)
+
+		now = now.Add(5 * time.Second)
+		w.record(1, 0, 1)
+
+		failures, slow, total := w.counts()
+		assert.Equal(t, int64(4), failures)
+		assert.Equal(t, int64(1), slow)
+		assert.Equal(t, int64(7), total)
+
+		now = now.Add(6 * time.Second)
+
+		failures, slow, total = w.counts()
+		assert.Equal(t, int64(1), failures)
+		assert.Equal(t, int64(0), slow)
+		assert.Equal(t, int64(2), total)
+	})
+}
+
+func TestExceedRate(t *testing.T) {
+	options := StorageOptions{FailureRateThreshold: 50, SlowCallRateThreshold: 75, MinimumNumberOfCalls: 4}
+
+	assert.False(t, exceedRate(0, 0, 0, options))
+	assert.False(t, exceedRate(3, 0, 3, options), "minimum number of calls is not reached")
+	assert.True(t, exceedRate(2, 0, 4, options))
+	assert.False(t, exceedRate(1, 2, 4, options))
+	assert.True(t, exceedRate(0, 3, 4, options))
+}